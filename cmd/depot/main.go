@@ -6,6 +6,10 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/modfin/depot"
 	"github.com/modfin/depot/internal/deps"
+	"github.com/modfin/depot/internal/deps/actions"
+	"github.com/modfin/depot/internal/deps/jar"
+	"github.com/modfin/depot/internal/deps/nuget"
+	"github.com/modfin/depot/internal/deps/sbom"
 	"github.com/modfin/depot/internal/depsdev"
 	"github.com/modfin/henry/slicez"
 	log "github.com/sirupsen/logrus"
@@ -21,10 +25,17 @@ func main() {
 
 	log.SetLevel(log.ErrorLevel)
 
+	if err := newApp().Run(os.Args); err != nil {
+		log.Error(err)
+		os.Exit(1)
+	}
+}
+
+func newApp() *cli.App {
 	var cache *deps.Cache
 	var config depot.Config
 
-	app := &cli.App{
+	return &cli.App{
 		Name:  "depot",
 		Usage: "a dep license tool",
 		Flags: []cli.Flag{
@@ -53,10 +64,51 @@ func main() {
 			},
 			&cli.StringSliceFlag{
 				Name:        "type",
-				Usage:       "Type of dep files we are looking for, go, npm, maven, cargo",
+				Usage:       "Type of dep files we are looking for, go, npm, maven, cargo, pypi, nuget, rubygems, composer, swift, pub, hex, vcpkg, conan, terraform, helm, actions, sbom. Java archives are only scanned when asked for with jar",
 				DefaultText: "All",
 				Aliases:     []string{"t"},
 			},
+			&cli.StringFlag{
+				Name:    "python-env",
+				Usage:   "Resolve unpinned python requirements against the packages installed in this virtualenv or site-packages directory",
+				EnvVars: []string{"DEPOT_PYTHON_ENV"},
+			},
+			&cli.StringFlag{
+				Name:    "depsdev-url",
+				Usage:   "Ask another deps.dev api, e.g. a mirror, overrides depsdev.url of the config file",
+				EnvVars: []string{"DEPOT_DEPSDEV_URL"},
+			},
+			&cli.DurationFlag{
+				Name:    "timeout",
+				Usage:   "Time out requests to deps.dev after this long, overrides depsdev.timeout of the config file",
+				EnvVars: []string{"DEPOT_TIMEOUT"},
+			},
+			&cli.StringFlag{
+				Name:    "user-agent",
+				Usage:   "Identify to deps.dev by this user agent, overrides depsdev.useragent of the config file",
+				EnvVars: []string{"DEPOT_USER_AGENT"},
+			},
+			&cli.StringFlag{
+				Name:    "proxy",
+				Usage:   "Reach deps.dev through this proxy, overrides depsdev.proxy of the config file and HTTPS_PROXY",
+				EnvVars: []string{"DEPOT_PROXY"},
+			},
+			&cli.IntFlag{
+				Name:    "retries",
+				Usage:   "Retry requests to deps.dev failing with server errors, 429, timeouts or reset connections this many times, overrides depsdev.retries of the config file",
+				EnvVars: []string{"DEPOT_RETRIES"},
+			},
+			&cli.Float64Flag{
+				Name:    "rate-limit",
+				Usage:   "Make at most this many requests a second to deps.dev, overrides depsdev.ratelimit of the config file",
+				EnvVars: []string{"DEPOT_RATE_LIMIT"},
+			},
+			&cli.IntFlag{
+				Name:    "concurrency",
+				Usage:   "Look up this many licenses at a time",
+				Value:   8,
+				EnvVars: []string{"DEPOT_CONCURRENCY"},
+			},
 			&cli.BoolFlag{
 				Name:    "verbose",
 				Aliases: []string{"v"},
@@ -98,11 +150,18 @@ func main() {
 				Name: "print",
 				Flags: []cli.Flag{
 					&cli.BoolFlag{Name: "lint"},
+					&cli.BoolFlag{Name: "strict", Usage: "Fail lint on dependency issues, such as unpinned versions, not only on unclear licenses"},
 				},
 				Action: func(c *cli.Context) error {
 					files := depFiles(c)
 
-					p := deps.New(cache)
+					p, err := newProcessor(c, cache, config)
+					if err != nil {
+						return err
+					}
+
+					// the deps of all files are looked up together, those deps.dev knows in batches
+					p.Prefetch(files...)
 
 					var allDeps []deps.Dep
 					for _, file := range files {
@@ -120,7 +179,7 @@ func main() {
 					fmt.Println(l.String())
 
 					if c.Bool("lint") {
-						return lint(allDeps)
+						return lint(allDeps, c.Bool("strict"))
 					}
 					return nil
 				},
@@ -129,11 +188,17 @@ func main() {
 				Name: "save",
 				Flags: []cli.Flag{
 					&cli.BoolFlag{Name: "lint"},
+					&cli.BoolFlag{Name: "strict", Usage: "Fail lint on dependency issues, such as unpinned versions, not only on unclear licenses"},
 				},
 				Action: func(c *cli.Context) error {
 					files := depFiles(c)
 
-					p := deps.New(cache)
+					p, err := newProcessor(c, cache, config)
+					if err != nil {
+						return err
+					}
+
+					p.Prefetch(files...)
 
 					var allDeps []deps.Dep
 					for _, file := range files {
@@ -151,13 +216,13 @@ func main() {
 					rootdir := c.String("root")
 					outname := c.String("license-file")
 
-					err := os.WriteFile(filepath.Join(rootdir, outname), []byte(l.String()), 0644)
+					err = os.WriteFile(filepath.Join(rootdir, outname), []byte(l.String()), 0644)
 					if err != nil {
 						return err
 					}
 
 					if c.Bool("lint") {
-						return lint(allDeps)
+						return lint(allDeps, c.Bool("strict"))
 					}
 					return nil
 				},
@@ -166,10 +231,16 @@ func main() {
 				Name: "lint",
 				Flags: []cli.Flag{
 					&cli.BoolFlag{Name: "verify", Usage: "Verify against existing --license-file, and fail on unsaved dependency or version changes"},
+					&cli.BoolFlag{Name: "strict", Usage: "Fail on dependency issues, such as unpinned versions, not only on unclear licenses"},
 				},
 				Action: func(c *cli.Context) error {
 					files := depFiles(c)
-					p := deps.New(cache)
+					p, err := newProcessor(c, cache, config)
+					if err != nil {
+						return err
+					}
+
+					p.Prefetch(files...)
 
 					var allDeps []deps.Dep
 					for _, file := range files {
@@ -181,7 +252,7 @@ func main() {
 						allDeps = append(allDeps, d...)
 					}
 					allDeps = fixDeps(config, allDeps)
-					err := lint(allDeps)
+					err = lint(allDeps, c.Bool("strict"))
 					if err != nil {
 						return err
 					}
@@ -193,14 +264,80 @@ func main() {
 			},
 		},
 	}
+}
 
-	if err := app.Run(os.Args); err != nil {
-		log.Error(err)
-		os.Exit(1)
+func newProcessor(c *cli.Context, cache *deps.Cache, config depot.Config) (*deps.Processor, error) {
+	depsDev := config.DepsDev
+	if c.IsSet("depsdev-url") {
+		depsDev.URL = c.String("depsdev-url")
+	}
+	if c.IsSet("timeout") {
+		depsDev.Timeout = c.Duration("timeout")
+	}
+	if c.IsSet("user-agent") {
+		depsDev.UserAgent = c.String("user-agent")
+	}
+	if c.IsSet("proxy") {
+		depsDev.Proxy = c.String("proxy")
 	}
+	if c.IsSet("retries") {
+		retries := c.Int("retries")
+		depsDev.Retries = &retries
+	}
+	if c.IsSet("rate-limit") {
+		depsDev.RateLimit = c.Float64("rate-limit")
+	}
+	client, err := deps.DepsDevClient(depsDev)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := deps.New(cache).WithDepsDev(client)
+	if err != nil {
+		return nil, err
+	}
+	p, err = p.WithProviders(config.Providers)
+	if err != nil {
+		return nil, err
+	}
+	return p.WithPythonEnv(c.String("python-env")).WithConcurrency(c.Int("concurrency")), nil
 }
 
-func lint(allDeps []deps.Dep) error {
+func lint(allDeps []deps.Dep, strict bool) error {
+
+	issues := slicez.Filter(allDeps, func(d deps.Dep) bool {
+		return len(d.Issues) > 0
+	})
+	issues = slicez.SortFunc(issues, func(a, b deps.Dep) bool {
+		return a.Key() < b.Key()
+	})
+	if len(issues) > 0 {
+		log.Error("There are dependencies with issues:")
+		for _, d := range issues {
+			for _, issue := range d.Issues {
+				log.Errorf("- %s %s %s: %s", d.Type, d.Name, d.Version, issue)
+			}
+		}
+	}
+
+	// warnings are reported alongside the issues, but never fail lint
+	warnings := slicez.Filter(allDeps, func(d deps.Dep) bool {
+		return len(d.Warnings) > 0
+	})
+	warnings = slicez.SortFunc(warnings, func(a, b deps.Dep) bool {
+		return a.Key() < b.Key()
+	})
+	warnings = slicez.UniqBy(warnings, func(d deps.Dep) string {
+		return d.Key()
+	})
+	if len(warnings) > 0 {
+		log.Error("There are dependencies with license warnings:")
+		for _, d := range warnings {
+			for _, warning := range d.Warnings {
+				log.Errorf("- %s %s %s: %s", d.Type, d.Name, d.Version, warning)
+			}
+		}
+	}
 
 	failingDeps := slicez.Filter(allDeps, func(d deps.Dep) bool {
 		return slicez.ContainsFunc(d.License, func(e string) bool {
@@ -215,10 +352,17 @@ func lint(allDeps []deps.Dep) error {
 		log.Error("There are dependencies with unclear license, address them in .depot.yml")
 		log.Error("Failing dependencies are:")
 		for _, d := range failingDeps {
+			if len(d.Errors) > 0 {
+				log.Errorf("- %s %s %s: %s", d.Type, d.Name, d.Version, strings.Join(d.Errors, "; "))
+				continue
+			}
 			log.Errorf("- %s %s %s", d.Type, d.Name, d.Version)
 		}
 		return errors.New("failed lint")
 	}
+	if strict && len(issues) > 0 {
+		return errors.New("failed lint, dependencies have issues")
+	}
 	return nil
 }
 
@@ -247,7 +391,7 @@ func fixDeps(config depot.Config, ds []deps.Dep) []deps.Dep {
 	for _, d := range ds {
 
 		_, found := slicez.Find(config.Dependency.Ignore, func(e depot.Dependency) bool {
-			return e.Type == string(d.Type) && e.Name == d.Name && (d.Version == e.Version || e.Version == "*" || e.Version == "")
+			return matches(e, d)
 		})
 
 		if found {
@@ -255,7 +399,7 @@ func fixDeps(config depot.Config, ds []deps.Dep) []deps.Dep {
 		}
 
 		match, found := slicez.Find(config.Dependency.Licenses, func(e depot.Dependency) bool {
-			return e.Type == string(d.Type) && e.Name == d.Name && (d.Version == e.Version || e.Version == "*" || e.Version == "")
+			return matches(e, d)
 		})
 
 		if found {
@@ -268,6 +412,16 @@ func fixDeps(config depot.Config, ds []deps.Dep) []deps.Dep {
 
 }
 
+// matches tells if a .depot.yml entry applies to the dependency, comparing names and versions the way the ecosystem does
+func matches(e depot.Dependency, d deps.Dep) bool {
+	if e.Type != string(d.Type) {
+		return false
+	}
+	name, version := deps.Canonical(d.Type, d.Name, d.Version)
+	ename, eversion := deps.Canonical(d.Type, e.Name, e.Version)
+	return name == ename && (version == eversion || e.Version == "*" || e.Version == "")
+}
+
 func depFiles(c *cli.Context) []string {
 
 	if c.Args().Len() > 0 {
@@ -284,21 +438,60 @@ func findDepFiles(root string, recurse bool, types []string) []string {
 
 		base := filepath.Base(path)
 
-		if path != root && !recurse && info.IsDir() {
+		// workflows are looked for in the repository root also when not recursing
+		if path != root && !recurse && info.IsDir() && !isWorkflowDir(root, path) {
+			return filepath.SkipDir
+		}
+
+		// ignoring installed dependencies
+		if path != root && info.IsDir() && (base == "node_modules" || base == ".build") {
+			return filepath.SkipDir
+		}
+		if path != root && info.IsDir() && base == "deps" && exists(filepath.Join(filepath.Dir(path), "mix.exs")) {
+			return filepath.SkipDir
+		}
+		// and the vcpkg ports tree, when vcpkg is kept within the repository
+		if path != root && info.IsDir() && (base == "vcpkg_installed" || exists(filepath.Join(path, ".vcpkg-root"))) {
+			return filepath.SkipDir
+		}
+
+		// and subcharts helm dependency build fetched
+		if path != root && info.IsDir() && base == "charts" && exists(filepath.Join(filepath.Dir(path), "Chart.yaml")) {
+			return filepath.SkipDir
+		}
+		if path != root && info.IsDir() && base == ".terraform" {
 			return filepath.SkipDir
 		}
 
-		// ignoring hidden files
+		// ignoring hidden files, but the terraform lock file
+		if strings.ToLower(base) == ".terraform.lock.hcl" {
+			if len(types) == 0 || slicez.Contains(types, string(depsdev.TERRAFORM)) {
+				files = append(files, path)
+			}
+			return nil
+		}
 		if path != root && strings.HasPrefix(base, ".") {
 			return nil
 		}
 
-		// ignoring node_modules
-		if path != root && info.IsDir() && base == "node_modules" {
-			return filepath.SkipDir
+		// java archives are usually build output, only scan them when asked for
+		if !info.IsDir() && jar.IsArchive(base) {
+			if slicez.Contains(types, "jar") {
+				files = append(files, path)
+			}
+			return nil
 		}
 
 		var t string
+		if nuget.IsProject(base) {
+			t = string(depsdev.NUGET)
+		}
+		if sbom.IsSBOM(base) {
+			t = "sbom"
+		}
+		if actions.IsWorkflow(path) || actions.IsAction(base) {
+			t = string(depsdev.ACTIONS)
+		}
 		switch strings.ToLower(base) {
 		case "package-lock.json":
 			t = string(depsdev.NPM)
@@ -308,8 +501,26 @@ func findDepFiles(root string, recurse bool, types []string) []string {
 			t = string(depsdev.MAVEN)
 		case "cargo.lock":
 			t = string(depsdev.CARGO)
-		case "requirements.txt":
+		case "requirements.txt", "pyproject.toml", "poetry.lock", "pipfile.lock", "pdm.lock", "uv.lock":
 			t = string(depsdev.PYPI)
+		case "packages.lock.json", "packages.config":
+			t = string(depsdev.NUGET)
+		case "gemfile.lock":
+			t = string(depsdev.RUBYGEMS)
+		case "composer.lock":
+			t = string(depsdev.COMPOSER)
+		case "package.resolved":
+			t = string(depsdev.SWIFT)
+		case "pubspec.lock":
+			t = string(depsdev.PUB)
+		case "mix.lock":
+			t = string(depsdev.HEX)
+		case "vcpkg.json":
+			t = string(depsdev.VCPKG)
+		case "conan.lock":
+			t = string(depsdev.CONAN)
+		case "chart.lock":
+			t = string(depsdev.HELM)
 		}
 
 		if t != "" {
@@ -324,6 +535,16 @@ func findDepFiles(root string, recurse bool, types []string) []string {
 
 }
 
+func isWorkflowDir(root string, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && (rel == ".github" || rel == filepath.Join(".github", "workflows"))
+}
+
+func exists(fileName string) bool {
+	_, err := os.Stat(fileName)
+	return err == nil
+}
+
 func touch(fileName string) {
 	_, err := os.Stat(fileName)
 	if os.IsNotExist(err) {
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/depsdev/depsdevtest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixture copies a project from testdata, depot writes its license and cache files next to it
+func fixture(t *testing.T, name string) string {
+	dir := t.TempDir()
+	entries, err := os.ReadDir(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join("testdata", name, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, e.Name()), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSave(t *testing.T) {
+	srv := depsdevtest.NewServer().Add(depsdev.NPM, "express", "4.18.2", "MIT")
+	defer srv.Close()
+
+	dir := fixture(t, "npm")
+	for i := 0; i < 2; i++ {
+		err := newApp().Run([]string{"depot", "--root", dir, "--depsdev-url", srv.URL, "save", "--lint"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "LICENSES_DEP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+MIT: 1
+---
+========================================================================
+MIT
+ https://spdx.org/licenses/MIT.html
+========================================================================
+
+ [package-lock.json]
+   express 4.18.2
+
+`
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Fatal(diff)
+	}
+	// dev dependencies are not looked up, and the second run is answered by the cache file
+	if got := srv.Batches(); got != 1 {
+		t.Fatalf("expected a single batch for express, got %d", got)
+	}
+	if got := srv.Requests(); len(got) != 0 {
+		t.Fatalf("expected express to be requested in the batch, got %v", got)
+	}
+}
+
+func TestLintUnknown(t *testing.T) {
+	srv := depsdevtest.NewServer()
+	defer srv.Close()
+
+	dir := fixture(t, "npm")
+	err := newApp().Run([]string{"depot", "--root", dir, "--depsdev-url", srv.URL, "lint"})
+	if err == nil {
+		t.Fatal("expected lint to fail on the license of express deps.dev does not know")
+	}
+}
+
+func TestLintUnpinned(t *testing.T) {
+	srv := depsdevtest.NewServer()
+	defer srv.Close()
+
+	// an unpinned requirement has no license to be unclear about, it is an issue failing only strict lint
+	dir := fixture(t, "pypi")
+	if err := newApp().Run([]string{"depot", "--root", dir, "--depsdev-url", srv.URL, "lint"}); err != nil {
+		t.Fatalf("expected lint to pass with an unpinned requirement, got %v", err)
+	}
+	if err := newApp().Run([]string{"depot", "--root", dir, "--depsdev-url", srv.URL, "lint", "--strict"}); err == nil {
+		t.Fatal("expected strict lint to fail on the unpinned requirement")
+	}
+	if got := srv.Requests(); len(got) != 0 || srv.Batches() != 0 {
+		t.Fatalf("expected the unpinned requirement not to be looked up, got %v and %d batches", got, srv.Batches())
+	}
+}
+
+func TestFindDepFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, path := range []string{"testdata/package-lock.json", "node_modules/express/package-lock.json"} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(path)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, path), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// testdata is a directory as any other, installed dependencies are not
+	got := findDepFiles(root, true, nil)
+	want := []string{filepath.Join(root, "testdata/package-lock.json")}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
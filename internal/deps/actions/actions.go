@@ -0,0 +1,122 @@
+package actions
+
+import (
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Workflow is the parts of a workflow, .github/workflows/*.yml, or of an action metadata file, action.yml,
+// that use other actions. Jobs use reusable workflows or run steps, composite actions run steps.
+type Workflow struct {
+	Jobs map[string]struct {
+		Uses  string `yaml:"uses"`
+		Steps []Step `yaml:"steps"`
+	} `yaml:"jobs"`
+	Runs struct {
+		Using string `yaml:"using"`
+		Steps []Step `yaml:"steps"`
+	} `yaml:"runs"`
+}
+
+type Step struct {
+	Uses string `yaml:"uses"`
+}
+
+// IsWorkflow tells if a file is a workflow, kept in .github/workflows
+func IsWorkflow(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	dir := filepath.ToSlash(filepath.Dir(path))
+	return (ext == ".yml" || ext == ".yaml") && (dir == ".github/workflows" || strings.HasSuffix(dir, "/.github/workflows"))
+}
+
+// IsAction tells if a file name is that of an action metadata file
+func IsAction(name string) bool {
+	name = strings.ToLower(name)
+	return name == "action.yml" || name == "action.yaml"
+}
+
+func Read(path string) (Workflow, error) {
+	var w Workflow
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return w, err
+	}
+	err = yaml.Unmarshal(b, &w)
+	return w, err
+}
+
+// Uses lists the uses: references of all jobs, ordered by job id, and steps
+func (w Workflow) Uses() []string {
+	var uses []string
+	for _, id := range slicez.Sort(mapz.Keys(w.Jobs)) {
+		job := w.Jobs[id]
+		if job.Uses != "" {
+			uses = append(uses, job.Uses)
+		}
+		for _, s := range job.Steps {
+			if s.Uses != "" {
+				uses = append(uses, s.Uses)
+			}
+		}
+	}
+	for _, s := range w.Runs.Steps {
+		if s.Uses != "" {
+			uses = append(uses, s.Uses)
+		}
+	}
+	return uses
+}
+
+// Ref is a reference to an action or reusable workflow in a repository, owner/repo[/path]@ref
+type Ref struct {
+	Owner string
+	Repo  string
+	Path  string
+	Ref   string
+}
+
+// ParseRef parses a uses: reference. Actions within the repository, ./path, and docker images, docker://image,
+// are not references to other repositories and are not parsed.
+func ParseRef(uses string) (Ref, bool) {
+	uses = strings.TrimSpace(uses)
+	if strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "docker://") {
+		return Ref{}, false
+	}
+	action, ref, found := strings.Cut(uses, "@")
+	if !found || ref == "" {
+		return Ref{}, false
+	}
+	parts := strings.SplitN(action, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return Ref{}, false
+	}
+	r := Ref{Owner: parts[0], Repo: parts[1], Ref: ref}
+	if len(parts) == 3 {
+		r.Path = parts[2]
+	}
+	return r, true
+}
+
+// Name is the repository of the action, owner/repo, which is what licenses apply to
+func (r Ref) Name() string {
+	return r.Owner + "/" + r.Repo
+}
+
+func (r Ref) String() string {
+	if r.Path != "" {
+		return r.Name() + "/" + r.Path + "@" + r.Ref
+	}
+	return r.Name() + "@" + r.Ref
+}
+
+var sha = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// Pinned is true for refs that are a full commit sha, tags and branches can be moved to other code
+func (r Ref) Pinned() bool {
+	return sha.MatchString(r.Ref)
+}
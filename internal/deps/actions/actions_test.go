@@ -0,0 +1,74 @@
+package actions
+
+import (
+	"github.com/modfin/henry/slicez"
+	"testing"
+)
+
+func TestUses(t *testing.T) {
+	tests := map[string][]string{
+		"testdata/.github/workflows/ci.yml": {
+			"actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11",
+			"docker://alpine:3.19",
+			"github/codeql-action/init@main",
+			"octo-org/workflows/.github/workflows/release.yml@v1",
+			"actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11",
+			"actions/setup-go@v4",
+			"./.github/actions/notify",
+		},
+		"testdata/action.yml": {
+			"actions/github-script@60a0d83039c74a4aee543508d2ffcb1c3799cdea",
+		},
+	}
+	for path, want := range tests {
+		w, err := Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Uses(); !slicez.Equal(got, want) {
+			t.Fatalf("%s: expected %v, got %v", path, want, got)
+		}
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		uses   string
+		name   string
+		ok     bool
+		pinned bool
+	}{
+		{"actions/checkout@b4ffde65f46336ab88eb53be808477a3936bae11", "actions/checkout", true, true},
+		{"actions/setup-go@v4", "actions/setup-go", true, false},
+		{"github/codeql-action/init@main", "github/codeql-action", true, false},
+		{"octo-org/workflows/.github/workflows/release.yml@v1", "octo-org/workflows", true, false},
+		{"actions/checkout@b4ffde6", "actions/checkout", true, false},
+		{"./.github/actions/notify", "", false, false},
+		{"docker://alpine:3.19", "", false, false},
+		{"actions/checkout", "", false, false},
+	}
+	for _, test := range tests {
+		ref, ok := ParseRef(test.uses)
+		if ok != test.ok {
+			t.Fatalf("%s: expected parsed %v, got %v", test.uses, test.ok, ok)
+		}
+		if ok && (ref.Name() != test.name || ref.Pinned() != test.pinned) {
+			t.Fatalf("%s: expected %s pinned %v, got %s pinned %v", test.uses, test.name, test.pinned, ref.Name(), ref.Pinned())
+		}
+	}
+}
+
+func TestIsWorkflow(t *testing.T) {
+	for path, want := range map[string]bool{
+		".github/workflows/ci.yml":        true,
+		"repo/.github/workflows/ci.yaml":  true,
+		".github/workflows/README.md":     false,
+		".github/dependabot.yml":          false,
+		"deploy/workflows/ci.yml":         false,
+		".github/workflows/nested/ci.yml": false,
+	} {
+		if got := IsWorkflow(path); got != want {
+			t.Fatalf("%s: expected %v, got %v", path, want, got)
+		}
+	}
+}
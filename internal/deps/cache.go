@@ -5,24 +5,34 @@ import (
 	"github.com/modfin/henry/mapz"
 	"github.com/modfin/henry/slicez"
 	"os"
+	"sync"
 )
 
+// Cache is the licenses looked up in earlier runs, by dep key. It is safe for concurrent use.
 type Cache struct {
 	file string
+	mu   sync.RWMutex
 	c    map[string]Dep
 }
 
 func (c *Cache) Put(dep Dep) {
+	dep.Name, dep.Version = Canonical(dep.Type, dep.Name, dep.Version)
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.c[dep.Key()] = dep
 }
 
 func (c *Cache) Get(key string) (Dep, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	v, ok := c.c[key]
 	return v, ok
 }
 
 func (c *Cache) Save() error {
+	c.mu.RLock()
 	deps := mapz.Values(c.c)
+	c.mu.RUnlock()
 	deps = slicez.SortFunc(deps, func(a, b Dep) bool {
 		return a.Key() < b.Key()
 	})
@@ -54,6 +64,12 @@ func NewCache(file string) (*Cache, error) {
 		return &c, err
 	}
 
+	// Entries written before names were normalized, e.g. pypi Django and django, are merged into one
+	deps = slicez.Map(deps, func(a Dep) Dep {
+		a.Name, a.Version = Canonical(a.Type, a.Name, a.Version)
+		return a
+	})
+
 	c.c = slicez.KeyBy(deps, func(a Dep) string {
 		return a.Key()
 
@@ -1,5 +1,9 @@
 package cargo
 
+import (
+	"strings"
+)
+
 type Pkg struct {
 	Name         string   `toml:"name"`
 	Version      string   `toml:"version"`
@@ -10,3 +14,103 @@ type Pkg struct {
 type Lockfile struct {
 	Packages []Pkg `toml:"package"`
 }
+
+// Local is true for packages without a source, i.e. workspace members and path dependencies
+func (p Pkg) Local() bool {
+	return p.Source == ""
+}
+
+type SourceKind string
+
+const SourceLocal SourceKind = "path"
+const SourceCratesIO SourceKind = "crates.io"
+const SourceRegistry SourceKind = "registry"
+const SourceGit SourceKind = "git"
+
+const cratesIOIndex = "registry+https://github.com/rust-lang/crates.io-index"
+const cratesIOSparseIndex = "sparse+https://index.crates.io/"
+
+// Kind classifies where the package comes from, by its source in the lockfile,
+// e.g. registry+https://github.com/rust-lang/crates.io-index or git+https://github.com/foo/bar?branch=main#4ab3f0c
+func (p Pkg) Kind() SourceKind {
+	switch {
+	case p.Source == "":
+		return SourceLocal
+	case p.Source == cratesIOIndex || p.Source == cratesIOSparseIndex:
+		return SourceCratesIO
+	case strings.HasPrefix(p.Source, "git+"):
+		return SourceGit
+	}
+	return SourceRegistry
+}
+
+// Rev is the commit a git sourced package is locked at
+func (p Pkg) Rev() string {
+	if p.Kind() != SourceGit {
+		return ""
+	}
+	_, rev, _ := strings.Cut(p.Source, "#")
+	return rev
+}
+
+// Dependency is an entry of Pkg.Dependencies. Cargo only writes the version, and source,
+// when the lockfile contains more than one package by that name, e.g. "rand 0.8.5"
+// or "rand 0.8.5 (registry+https://github.com/rust-lang/crates.io-index)"
+type Dependency struct {
+	Name    string
+	Version string
+	Source  string
+}
+
+func ParseDependency(s string) Dependency {
+	var d Dependency
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, " ("); i >= 0 && strings.HasSuffix(s, ")") {
+		d.Source = s[i+2 : len(s)-1]
+		s = s[:i]
+	}
+	d.Name, d.Version, _ = strings.Cut(s, " ")
+	return d
+}
+
+// Matches tells if the dependency reference points at the package
+func (d Dependency) Matches(p Pkg) bool {
+	if d.Name != p.Name {
+		return false
+	}
+	if d.Version != "" && d.Version != p.Version {
+		return false
+	}
+	if d.Source != "" && d.Source != p.Source {
+		return false
+	}
+	return true
+}
+
+// Manifest is the parts of Cargo.toml we care about
+type Manifest struct {
+	Package struct {
+		Name    string `toml:"name"`
+		License any    `toml:"license"`
+	} `toml:"package"`
+	Workspace struct {
+		Members []string `toml:"members"`
+		Exclude []string `toml:"exclude"`
+		Package struct {
+			License string `toml:"license"`
+		} `toml:"package"`
+	} `toml:"workspace"`
+}
+
+// License is the SPDX expression in the license field of the package. Inherited is true
+// when it is declared as license.workspace = true and has to be read from the workspace root.
+func (m Manifest) License() (license string, inherited bool) {
+	switch l := m.Package.License.(type) {
+	case string:
+		return l, false
+	case map[string]any:
+		inherit, _ := l["workspace"].(bool)
+		return "", inherit
+	}
+	return "", false
+}
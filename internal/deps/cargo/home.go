@@ -0,0 +1,128 @@
+package cargo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Home is where cargo keeps its registry sources and git checkouts, $CARGO_HOME or ~/.cargo
+func Home() string {
+	if home := os.Getenv("CARGO_HOME"); home != "" {
+		return home
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, ".cargo")
+}
+
+func ReadManifest(path string) (Manifest, error) {
+	var manifest Manifest
+	_, err := toml.DecodeFile(path, &manifest)
+	return manifest, err
+}
+
+// FindManifest locates the Cargo.toml of a package among the sources cargo has unpacked in its home,
+// registry/src/<index>/<name>-<version> for registries and git/checkouts/<repo>/<short rev> for git
+func FindManifest(home string, p Pkg) (manifest string, root string, err error) {
+	switch p.Kind() {
+	case SourceCratesIO, SourceRegistry:
+		matches, _ := filepath.Glob(filepath.Join(home, "registry", "src", "*", p.Name+"-"+p.Version, "Cargo.toml"))
+
+		// The index directories are named <host>-<hash>, pick the one belonging to the source. A package of another
+		// registry is another package, only a source of unknown host takes the single one there is.
+		host := sourceHost(p.Source)
+		for _, m := range matches {
+			index := filepath.Base(filepath.Dir(filepath.Dir(m)))
+			if host != "" && strings.HasPrefix(index, host+"-") {
+				return m, filepath.Dir(m), nil
+			}
+		}
+		if host == "" && len(matches) == 1 {
+			return matches[0], filepath.Dir(matches[0]), nil
+		}
+
+	case SourceGit:
+		rev := p.Rev()
+		if len(rev) > 7 {
+			rev = rev[:7]
+		}
+		if rev == "" {
+			break
+		}
+		checkouts, _ := filepath.Glob(filepath.Join(home, "git", "checkouts", "*", rev))
+		for _, checkout := range checkouts {
+			var found string
+			_ = filepath.WalkDir(checkout, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				if found != "" {
+					return filepath.SkipAll
+				}
+				if d.IsDir() && (d.Name() == "target" || strings.HasPrefix(d.Name(), ".")) && path != checkout {
+					return filepath.SkipDir
+				}
+				if d.Name() != "Cargo.toml" {
+					return nil
+				}
+				m, err := ReadManifest(path)
+				if err == nil && m.Package.Name == p.Name {
+					found = path
+				}
+				return nil
+			})
+			if found != "" {
+				return found, checkout, nil
+			}
+		}
+	}
+	return "", "", fmt.Errorf("could not find %s %s from %s in %s: %w", p.Name, p.Version, p.Source, home, fs.ErrNotExist)
+}
+
+// LicenseOf reads the license declared in a package manifest, looking for inherited
+// workspace licenses in the directories between the manifest and root
+func LicenseOf(manifestPath string, root string) (string, error) {
+	m, err := ReadManifest(manifestPath)
+	if err != nil {
+		return "", err
+	}
+	license, inherited := m.License()
+	if !inherited {
+		if license == "" {
+			return "", errors.New("no license field in " + manifestPath)
+		}
+		return license, nil
+	}
+
+	root = filepath.Clean(root)
+	for dir := filepath.Dir(filepath.Dir(manifestPath)); strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		ws, err := ReadManifest(filepath.Join(dir, "Cargo.toml"))
+		if err == nil && ws.Workspace.Package.License != "" {
+			return ws.Workspace.Package.License, nil
+		}
+		if dir == root {
+			break
+		}
+	}
+	return "", errors.New("could not find workspace license inherited by " + manifestPath)
+}
+
+func sourceHost(source string) string {
+	_, uri, found := strings.Cut(source, "+")
+	if !found {
+		return ""
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
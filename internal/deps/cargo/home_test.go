@@ -0,0 +1,67 @@
+package cargo
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func write(t *testing.T, path string, content string) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalLicense(t *testing.T) {
+	home := t.TempDir()
+
+	write(t, filepath.Join(home, "registry/src/index.crates.io-6f17d22bba15001f/rand-0.8.5/Cargo.toml"),
+		"[package]\nname = \"rand\"\nlicense = \"MIT OR Apache-2.0\"\n")
+	write(t, filepath.Join(home, "registry/src/crates.example.com-0123456789abcdef/rand-0.8.5/Cargo.toml"),
+		"[package]\nname = \"rand\"\nlicense = \"LicenseRef-Proprietary\"\n")
+	write(t, filepath.Join(home, "git/checkouts/tokio-c1f4b1d5cf8fcb9d/4ab3f0c/Cargo.toml"),
+		"[workspace]\nmembers = [\"tokio\"]\n\n[workspace.package]\nlicense = \"MIT\"\n")
+	write(t, filepath.Join(home, "git/checkouts/tokio-c1f4b1d5cf8fcb9d/4ab3f0c/tokio/Cargo.toml"),
+		"[package]\nname = \"tokio\"\nlicense.workspace = true\n")
+
+	tests := []struct {
+		pkg  Pkg
+		kind SourceKind
+		want string
+	}{
+		{Pkg{Name: "rand", Version: "0.8.5", Source: "sparse+https://index.crates.io/"}, SourceCratesIO, "MIT OR Apache-2.0"},
+		{Pkg{Name: "rand", Version: "0.8.5", Source: "sparse+https://crates.example.com/index/"}, SourceRegistry, "LicenseRef-Proprietary"},
+		{Pkg{Name: "tokio", Version: "1.35.0", Source: "git+https://github.com/example/tokio?branch=fix#4ab3f0c9e2d1"}, SourceGit, "MIT"},
+	}
+	for _, test := range tests {
+		if test.pkg.Kind() != test.kind {
+			t.Fatalf("expected %s to be %s, got %s", test.pkg.Source, test.kind, test.pkg.Kind())
+		}
+		manifest, root, err := FindManifest(home, test.pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		license, err := LicenseOf(manifest, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if license != test.want {
+			t.Fatalf("expected %s for %s, got %s", test.want, test.pkg.Source, license)
+		}
+	}
+
+	// a crate of the same name and version from another registry is not the one asked for
+	write(t, filepath.Join(home, "registry/src/crates.example.com-0123456789abcdef/billing-1.0.0/Cargo.toml"),
+		"[package]\nname = \"billing\"\nlicense = \"LicenseRef-Proprietary\"\n")
+	_, _, err := FindManifest(home, Pkg{Name: "billing", Version: "1.0.0", Source: "sparse+https://index.crates.io/"})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected billing not to be found among the crates.io sources, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package deps
+
+import (
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/henry/slicez"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultClassifierRoots are where packages are conventionally vendored, next to the lockfile
+var defaultClassifierRoots = []string{"vendor", "third_party"}
+
+// ClassifierProvider is an offline license provider, recognising the license texts of packages copied into
+// local directories, as <root>/<name>@<version> or <root>/<name>
+type ClassifierProvider struct {
+	// roots are where packages are looked for, if not in the default roots next to the lockfile
+	roots []string
+}
+
+// NewClassifier looks for packages in the given directories, or the vendor and third_party directories next to
+// the lockfile
+func NewClassifier(roots ...string) ClassifierProvider {
+	return ClassifierProvider{roots: roots}
+}
+
+func (c ClassifierProvider) Name() string {
+	return Classifier
+}
+
+// Licenses recognises the licenses in the directory of a package, packages not found locally are ~unknown
+func (c ClassifierProvider) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	return c.LicensesIn("", depType, name, version)
+}
+
+// LicensesIn recognises the licenses of a package read from a lockfile in lockDir
+func (c ClassifierProvider) LicensesIn(lockDir string, depType depsdev.DepType, name string, version string) ([]string, error) {
+	dir := c.dirOf(lockDir, name, version)
+	if dir == "" {
+		return []string{"~unknown"}, nil
+	}
+	return dirLicense(depType, name, version, dir), nil
+}
+
+func (c ClassifierProvider) dirOf(lockDir string, name string, version string) string {
+	roots := c.roots
+	if len(roots) == 0 {
+		roots = slicez.Map(defaultClassifierRoots, func(root string) string {
+			return filepath.Join(lockDir, root)
+		})
+	}
+	// maven packages are group:artifact
+	name = filepath.FromSlash(strings.ReplaceAll(name, ":", "/"))
+	for _, root := range roots {
+		for _, dir := range []string{filepath.Join(root, name+"@"+version), filepath.Join(root, name)} {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				return dir
+			}
+		}
+	}
+	return ""
+}
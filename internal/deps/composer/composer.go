@@ -0,0 +1,85 @@
+package composer
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+)
+
+// LockFile is composer.lock, which carries the license of every package as declared in its composer.json
+type LockFile struct {
+	Packages    []Package `json:"packages"`
+	PackagesDev []Package `json:"packages-dev"`
+}
+
+type Package struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	License []string `json:"license"`
+	Type    string   `json:"type"`
+	Source  struct {
+		Type      string `json:"type"`
+		URL       string `json:"url"`
+		Reference string `json:"reference"`
+	} `json:"source"`
+	Dist struct {
+		Type string `json:"type"`
+		URL  string `json:"url"`
+	} `json:"dist"`
+	// Dev is set for packages read from packages-dev
+	Dev bool `json:"-"`
+}
+
+// Local is true for packages installed from a path repository, e.g. modules within the project itself
+func (p Package) Local() bool {
+	return p.Dist.Type == "path"
+}
+
+// Manifest is the parts of composer.json declaring dependencies
+type Manifest struct {
+	Name       string            `json:"name"`
+	Require    map[string]string `json:"require"`
+	RequireDev map[string]string `json:"require-dev"`
+}
+
+// Direct tells if a package is required by the project itself. Package names are case insensitive. Packages of
+// require-dev are not, the dev packages are those of packages-dev in the lockfile, and a package of require-dev in
+// packages is only there as a dependency of another package.
+func (m Manifest) Direct(name string) bool {
+	for req := range m.Require {
+		if strings.EqualFold(req, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// All are the packages of the lockfile, with Dev set for those from packages-dev
+func (l LockFile) All() []Package {
+	all := append([]Package{}, l.Packages...)
+	for _, p := range l.PackagesDev {
+		p.Dev = true
+		all = append(all, p)
+	}
+	return all
+}
+
+func ReadLockFile(path string) (LockFile, error) {
+	var l LockFile
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	err = json.Unmarshal(b, &l)
+	return l, err
+}
+
+func ReadManifest(path string) (Manifest, error) {
+	var m Manifest
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
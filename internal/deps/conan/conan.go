@@ -0,0 +1,235 @@
+package conan
+
+import (
+	"encoding/json"
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LockFile is conan.lock. Conan 2 lists references by context, conan 1 writes the graph with a node per package.
+type LockFile struct {
+	Version        string   `json:"version"`
+	Requires       []string `json:"requires"`
+	BuildRequires  []string `json:"build_requires"`
+	PythonRequires []string `json:"python_requires"`
+
+	GraphLock struct {
+		Nodes map[string]struct {
+			Ref           string   `json:"ref"`
+			Requires      []string `json:"requires"`
+			BuildRequires []string `json:"build_requires"`
+		} `json:"nodes"`
+	} `json:"graph_lock"`
+}
+
+// Reference is a recipe reference, name/version[@user/channel][#revision[%timestamp]]
+type Reference struct {
+	Name     string
+	Version  string
+	User     string
+	Channel  string
+	Revision string
+}
+
+func ParseReference(ref string) Reference {
+	var r Reference
+	ref, rev, _ := strings.Cut(ref, "#")
+	r.Revision, _, _ = strings.Cut(rev, "%")
+	ref, userChannel, _ := strings.Cut(ref, "@")
+	r.User, r.Channel, _ = strings.Cut(userChannel, "/")
+	r.Name, r.Version, _ = strings.Cut(ref, "/")
+	return r
+}
+
+// Package is a locked recipe, with Build set for tools only needed to build, conan's build requirements
+type Package struct {
+	Reference
+	Build bool
+	// Direct is only known from conan 1 lockfiles, which has the consumer as node 0
+	Direct bool
+}
+
+func ReadLockFile(path string) ([]Package, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var l LockFile
+	err = json.Unmarshal(b, &l)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgs []Package
+	for _, ref := range l.Requires {
+		pkgs = append(pkgs, Package{Reference: ParseReference(ref)})
+	}
+	for _, ref := range l.BuildRequires {
+		pkgs = append(pkgs, Package{Reference: ParseReference(ref), Build: true})
+	}
+
+	nodes := l.GraphLock.Nodes
+	if len(nodes) == 0 {
+		return pkgs, nil
+	}
+	build := map[string]bool{}
+	for _, n := range nodes {
+		for _, id := range n.BuildRequires {
+			build[id] = true
+		}
+	}
+	// build requirements of build requirements are build only too
+	for changed := true; changed; {
+		changed = false
+		for id, n := range nodes {
+			if !build[id] {
+				continue
+			}
+			for _, req := range n.Requires {
+				if !build[req] {
+					build[req], changed = true, true
+				}
+			}
+		}
+	}
+	direct := map[string]bool{}
+	for _, id := range nodes["0"].Requires {
+		direct[id] = true
+	}
+	for _, id := range slicez.Sort(mapz.Keys(nodes)) {
+		n := nodes[id]
+		if id == "0" || n.Ref == "" {
+			continue
+		}
+		pkgs = append(pkgs, Package{Reference: ParseReference(n.Ref), Build: build[id], Direct: direct[id]})
+	}
+	return pkgs, nil
+}
+
+var referenceRegexp = regexp.MustCompile(`["']([\w.+-]+)/[^"']+["']`)
+
+// Requirements reads the names of the recipes a conanfile.txt or conanfile.py in the directory requires directly.
+// For conanfile.py, which is python, only string references on lines setting or calling requires are recognised.
+func Requirements(projectDir string) ([]string, error) {
+	if b, err := os.ReadFile(filepath.Join(projectDir, "conanfile.txt")); err == nil {
+		var names []string
+		section := ""
+		for _, line := range strings.Split(string(b), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "[") {
+				section = line
+				continue
+			}
+			if section == "[requires]" && line != "" && !strings.HasPrefix(line, "#") {
+				names = append(names, ParseReference(line).Name)
+			}
+		}
+		return names, nil
+	}
+
+	b, err := os.ReadFile(filepath.Join(projectDir, "conanfile.py"))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, line := range strings.Split(string(b), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "requires") && !strings.HasPrefix(trimmed, "self.requires(") {
+			continue
+		}
+		for _, m := range referenceRegexp.FindAllStringSubmatch(line, -1) {
+			names = append(names, m[1])
+		}
+	}
+	return names, nil
+}
+
+// Home is the conan cache, $CONAN_HOME or ~/.conan2 for conan 2 and ~/.conan for conan 1
+func Home() []string {
+	var homes []string
+	if home := os.Getenv("CONAN_HOME"); home != "" {
+		homes = append(homes, home)
+	}
+	if home := os.Getenv("CONAN_USER_HOME"); home != "" {
+		homes = append(homes, filepath.Join(home, ".conan"))
+	}
+	if dir, err := os.UserHomeDir(); err == nil {
+		homes = append(homes, filepath.Join(dir, ".conan2"), filepath.Join(dir, ".conan"))
+	}
+	return homes
+}
+
+var nameRegexp = regexp.MustCompile(`(?m)^\s+name\s*=\s*["']([^"']+)["']`)
+
+// FindRecipe locates the exported conanfile.py of a recipe in the conan cache. Conan 1 keeps recipes at
+// data/<name>/<version>/<user>/<channel>/export, conan 2 in p/<name><hash>/e, which has to be matched by name
+// and, since recipes from conan center serve many versions, the version listed in conandata.yml.
+func FindRecipe(homes []string, r Reference) (string, bool) {
+	user, channel := r.User, r.Channel
+	if user == "" {
+		user, channel = "_", "_"
+	}
+	for _, home := range homes {
+		v1 := filepath.Join(home, "data", r.Name, r.Version, user, channel, "export", "conanfile.py")
+		if _, err := os.Stat(v1); err == nil {
+			return v1, true
+		}
+
+		prefix := r.Name
+		if len(prefix) > 5 {
+			prefix = prefix[:5]
+		}
+		exports, _ := filepath.Glob(filepath.Join(home, "p", prefix+"*", "e", "conanfile.py"))
+		for _, export := range exports {
+			b, err := os.ReadFile(export)
+			if err != nil {
+				continue
+			}
+			m := nameRegexp.FindStringSubmatch(string(b))
+			if m == nil || m[1] != r.Name {
+				continue
+			}
+			if hasVersion(string(b), filepath.Join(filepath.Dir(export), "conandata.yml"), r.Version) {
+				return export, true
+			}
+		}
+	}
+	return "", false
+}
+
+func hasVersion(conanfile string, conandata string, version string) bool {
+	if regexp.MustCompile(`(?m)^\s+version\s*=\s*["']` + regexp.QuoteMeta(version) + `["']`).MatchString(conanfile) {
+		return true
+	}
+	b, err := os.ReadFile(conandata)
+	if err != nil {
+		return false
+	}
+	return regexp.MustCompile(`(?m)^\s+["']?` + regexp.QuoteMeta(version) + `["']?:`).Match(b)
+}
+
+var licenseRegexp = regexp.MustCompile(`(?m)^\s+license\s*=\s*(\([^)]*\)|\[[^\]]*\]|"[^"]*"|'[^']*')`)
+var quotedRegexp = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// License reads the license attribute of a recipe, a string or a tuple of them
+func License(conanfile string) ([]string, error) {
+	b, err := os.ReadFile(conanfile)
+	if err != nil {
+		return nil, err
+	}
+	m := licenseRegexp.FindStringSubmatch(string(b))
+	if m == nil {
+		return nil, nil
+	}
+	var licenses []string
+	for _, q := range quotedRegexp.FindAllStringSubmatch(m[1], -1) {
+		if l := q[1] + q[2]; l != "" {
+			licenses = append(licenses, l)
+		}
+	}
+	return licenses, nil
+}
@@ -0,0 +1,70 @@
+package conan
+
+import (
+	"fmt"
+	"github.com/modfin/henry/slicez"
+	"testing"
+)
+
+func TestReadLockFile(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		want []string
+	}{
+		{"./testdata/v2/conan.lock", []string{
+			"zlib 1.3 false false",
+			"openssl 3.1.3 false false",
+			"fmt 10.1.1 false false",
+			"cmake 3.27.7 true false",
+		}},
+		{"./testdata/v1/conan.lock", []string{
+			"poco 1.12.4 false true",
+			"pcre2 10.42 false false",
+			"ninja 1.11.1 true false",
+			"re2c 3.0 true false",
+		}},
+	} {
+		pkgs, err := ReadLockFile(test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := slicez.Map(pkgs, func(p Package) string {
+			return fmt.Sprintf("%s %s %t %t", p.Name, p.Version, p.Build, p.Direct)
+		})
+		if !slicez.Equal(got, test.want) {
+			t.Fatalf("%s: expected %v, got %v", test.path, test.want, got)
+		}
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	r := ParseReference("pcre2/10.42@acme/stable#5b9e8e7f6a4c3d2b1a0f9e8d7c6b5a49%1695738937.282")
+	want := Reference{Name: "pcre2", Version: "10.42", User: "acme", Channel: "stable", Revision: "5b9e8e7f6a4c3d2b1a0f9e8d7c6b5a49"}
+	if r != want {
+		t.Fatalf("expected %+v, got %+v", want, r)
+	}
+}
+
+func TestRequirements(t *testing.T) {
+	names, err := Requirements("./testdata/v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicez.Equal(names, []string{"fmt", "openssl"}) {
+		t.Fatalf("unexpected requirements %v", names)
+	}
+}
+
+func TestRecipeLicense(t *testing.T) {
+	recipe, found := FindRecipe([]string{"./testdata/home"}, Reference{Name: "zlib", Version: "1.3"})
+	if !found {
+		t.Fatal("expected to find the zlib recipe")
+	}
+	licenses, err := License(recipe)
+	if err != nil || !slicez.Equal(licenses, []string{"Zlib"}) {
+		t.Fatalf("expected Zlib, got %v %v", licenses, err)
+	}
+	if _, found := FindRecipe([]string{"./testdata/home"}, Reference{Name: "zlib", Version: "1.2.11"}); found {
+		t.Fatal("did not expect to find zlib 1.2.11")
+	}
+}
@@ -0,0 +1,15 @@
+from conan import ConanFile
+from conan.tools.files import get
+
+
+class ZlibConan(ConanFile):
+    name = "zlib"
+    package_type = "library"
+    url = "https://github.com/conan-io/conan-center-index"
+    homepage = "https://zlib.net"
+    license = "Zlib"
+    description = ("A Massively Spiffy Yet Delicately Unobtrusive Compression Library "
+                   "(Also Free, Not to Mention Unencumbered by Patents)")
+
+    def source(self):
+        get(self, **self.conan_data["sources"][self.version], strip_root=True)
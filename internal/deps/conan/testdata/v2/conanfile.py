@@ -0,0 +1,13 @@
+from conan import ConanFile
+
+
+class RendererConan(ConanFile):
+    settings = "os", "arch", "compiler", "build_type"
+    generators = "CMakeDeps", "CMakeToolchain"
+
+    def requirements(self):
+        self.requires("fmt/10.1.1")
+        self.requires("openssl/[>=3.1 <4]")
+
+    def build_requirements(self):
+        self.tool_requires("cmake/3.27.7")
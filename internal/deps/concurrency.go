@@ -0,0 +1,228 @@
+package deps
+
+import (
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// deferred is the lookup of the licenses of a dep, made once the deps of the files read are known, so that they
+// are looked up together
+type deferred struct {
+	// dir is that of the file the dep is read from, next to which the local providers look for it
+	dir string
+	// fallback reads the licenses locally, of deps no provider knows
+	fallback func() []string
+}
+
+type request struct {
+	// dir is that of the file the dep is read from
+	dir     string
+	depType depsdev.DepType
+	name    string
+	version string
+}
+
+// flight is a lookup in progress, deps asked for again while it is wait for it rather than look up again
+type flight struct {
+	done    chan struct{}
+	license []string
+	err     error
+}
+
+// WithConcurrency looks up licenses with up to n lookups at a time, by default one
+func (pro *Processor) WithConcurrency(n int) *Processor {
+	pro.concurrency = n
+	return pro
+}
+
+// read is a file prefetched, its deps with their licenses looked up
+type read struct {
+	deps []Dep
+	err  error
+}
+
+// Prefetch reads many files, looking up the licenses of their deps together, and keeps them for FromFile to return.
+// Each file is read once. The deps are as when a file is read on its own, only looked up together.
+func (pro *Processor) Prefetch(paths ...string) {
+	reads := map[string]read{}
+	var deps []Dep
+	for _, path := range paths {
+		d, err := pro.fromFile(path)
+		reads[path] = read{deps: d, err: err}
+		deps = append(deps, d...)
+	}
+	pro.lookUp(deps)
+	for path, r := range reads {
+		r.deps, r.err = pro.resolve(r.deps, r.err)
+		reads[path] = r
+	}
+
+	pro.mu.Lock()
+	defer pro.mu.Unlock()
+	if pro.read == nil {
+		pro.read = map[string]read{}
+	}
+	for path, r := range reads {
+		pro.read[path] = r
+	}
+}
+
+// prefetched takes the deps of a file prefetched, they are returned once
+func (pro *Processor) prefetched(path string) (read, bool) {
+	pro.mu.Lock()
+	defer pro.mu.Unlock()
+	r, found := pro.read[path]
+	delete(pro.read, path)
+	return r, found
+}
+
+// resolve looks up the licenses deferred of the deps read from a file
+func (pro *Processor) resolve(deps []Dep, err error) ([]Dep, error) {
+	pro.lookUp(deps)
+	for i, d := range deps {
+		deps[i] = pro.resolved(d)
+	}
+	return deps, err
+}
+
+// resolved is a dep with the licenses deferred looked up, or read locally if no provider knows them
+func (pro *Processor) resolved(d Dep) Dep {
+	lookup := d.deferred
+	if lookup == nil {
+		return d
+	}
+	d.deferred = nil
+	d.License, _ = pro.licensesIn(lookup.dir, d.Type, d.Name, d.Version)
+	if unknown(d.License) && lookup.fallback != nil {
+		d.License = lookup.fallback()
+	}
+	if l, found := pro.lookup(d.Key()); found && slicez.Equal(l.License, d.License) {
+		d.Provenance = l.Provenance
+		d.Warnings = l.Warnings
+		d.Errors = l.Errors
+	}
+	return d
+}
+
+// lookUp looks up the licenses deferred of deps not known yet, those deps.dev serves are fetched from it in batches,
+// and then all are looked up by a bounded pool of workers
+func (pro *Processor) lookUp(deps []Dep) {
+	requests := map[string]request{}
+	for _, d := range deps {
+		if d.deferred == nil {
+			continue
+		}
+		key := d.Key()
+		if _, found := requests[key]; found || pro.known(key) {
+			continue
+		}
+		requests[key] = request{dir: d.deferred.dir, depType: d.Type, name: d.Name, version: d.Version}
+	}
+
+	keys := slicez.Sort(mapz.Keys(requests))
+	if len(keys) == 0 {
+		return
+	}
+	pro.batch(slicez.Map(keys, func(key string) request {
+		return requests[key]
+	}))
+
+	queue := make(chan request)
+	var wg sync.WaitGroup
+	for i := 0; i < min(max(pro.concurrency, 1), len(keys)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range queue {
+				_, _ = pro.licensesIn(r.dir, r.depType, r.name, r.version)
+			}
+		}()
+	}
+	for _, key := range keys {
+		queue <- requests[key]
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// known is whether the licenses of a dep have been looked up, in this run or an earlier one
+func (pro *Processor) known(key string) bool {
+	if _, found := pro.lookup(key); found {
+		return true
+	}
+	if pro.cache != nil {
+		if _, found := pro.cache.Get(key); found {
+			return true
+		}
+	}
+	return false
+}
+
+// batch fetches the versions to look up with deps.dev in batches, with the deps.dev client of their chain.
+// Versions deps.dev does not know, or failing batches, are looked up as usual.
+func (pro *Processor) batch(requests []request) {
+	var clients []*depsdev.Client
+	batches := map[*depsdev.Client][]depsdev.VersionKey{}
+	for _, r := range requests {
+		for _, provider := range pro.providers[r.depType] {
+			client, ok := provider.(*depsdev.Client)
+			if !ok {
+				continue
+			}
+			if _, found := batches[client]; !found {
+				clients = append(clients, client)
+			}
+			batches[client] = append(batches[client], depsdev.Key(r.depType, r.name, r.version))
+			break
+		}
+	}
+	for _, client := range clients {
+		log.Infof("deps.dev; requesting %d versions in batches", len(batches[client]))
+		if err := client.Batch(batches[client]); err != nil {
+			log.WithError(err).Warnf("deps.dev; could not request versions in batches, requesting them one by one")
+		}
+	}
+}
+
+// join waits for the lookup of a dep already in progress, or else registers the lookup the caller is to make
+func (pro *Processor) join(key string) (*flight, bool) {
+	pro.mu.Lock()
+	defer pro.mu.Unlock()
+	if f, found := pro.inflight[key]; found {
+		return f, true
+	}
+	if pro.inflight == nil {
+		pro.inflight = map[string]*flight{}
+	}
+	f := &flight{done: make(chan struct{})}
+	pro.inflight[key] = f
+	return f, false
+}
+
+// land completes a lookup, handing its result to those waiting for it
+func (pro *Processor) land(key string, f *flight, license []string, err error) {
+	f.license, f.err = license, err
+	pro.mu.Lock()
+	delete(pro.inflight, key)
+	pro.mu.Unlock()
+	close(f.done)
+}
+
+func (pro *Processor) lookup(key string) (Dep, bool) {
+	pro.mu.Lock()
+	defer pro.mu.Unlock()
+	dep, found := pro.lookups[key]
+	return dep, found
+}
+
+func (pro *Processor) record(key string, dep Dep) {
+	pro.mu.Lock()
+	defer pro.mu.Unlock()
+	if pro.lookups == nil {
+		pro.lookups = map[string]Dep{}
+	}
+	pro.lookups[key] = dep
+}
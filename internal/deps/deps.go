@@ -1,35 +1,80 @@
 package deps
 
 import (
-	"bufio"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/BurntSushi/toml"
 	"github.com/modfin/depot"
+	"github.com/modfin/depot/internal/deps/actions"
 	"github.com/modfin/depot/internal/deps/cargo"
+	"github.com/modfin/depot/internal/deps/composer"
+	"github.com/modfin/depot/internal/deps/conan"
+	"github.com/modfin/depot/internal/deps/gem"
+	"github.com/modfin/depot/internal/deps/helm"
+	"github.com/modfin/depot/internal/deps/jar"
+	"github.com/modfin/depot/internal/deps/mix"
 	"github.com/modfin/depot/internal/deps/npm"
+	"github.com/modfin/depot/internal/deps/nuget"
 	"github.com/modfin/depot/internal/deps/pom"
+	"github.com/modfin/depot/internal/deps/pub"
+	"github.com/modfin/depot/internal/deps/pypi"
+	"github.com/modfin/depot/internal/deps/rootfs"
+	"github.com/modfin/depot/internal/deps/sbom"
+	"github.com/modfin/depot/internal/deps/swift"
+	"github.com/modfin/depot/internal/deps/terraform"
+	"github.com/modfin/depot/internal/deps/vcpkg"
 	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/spdx"
 	"github.com/modfin/henry/exp/containerz/set"
 	"github.com/modfin/henry/mapz"
 	"github.com/modfin/henry/slicez"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/mod/modfile"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
-	"unicode"
+	"sync"
 )
 
 func New(cache *Cache) *Processor {
+	depsDev := depsdev.New()
+	providers, _ := Chains(nil, depsDev)
 	return &Processor{
-		cache: cache,
+		cache:     cache,
+		depsDev:   depsDev,
+		providers: providers,
+		lookups:   map[string]Dep{},
 	}
 }
 
 type Processor struct {
 	cache *Cache
+	// depsDev is the client shared by the deps.dev providers of every dep type
+	depsDev   *depsdev.Client
+	config    []depot.Provider
+	providers map[depsdev.DepType][]LicenseProvider
+	checkers  map[depsdev.DepType][]LicenseChecker
+	pythonEnv string
+
+	// concurrency is how many lookups are made at a time
+	concurrency int
+
+	mu sync.Mutex
+	// read are the files prefetched that are not to be read again, by path
+	read map[string]read
+	// lookups are the licenses looked up, by dep key, with the provider that knew them
+	lookups  map[string]Dep
+	inflight map[string]*flight
+}
+
+// WithPythonEnv resolves unpinned python requirements against the packages installed in
+// a python environment, a virtualenv or site-packages directory
+func (pro *Processor) WithPythonEnv(env string) *Processor {
+	pro.pythonEnv = env
+	return pro
 }
 
 func ToLicense(rootdir string, deps []Dep) depot.LicenseStructure {
@@ -37,6 +82,9 @@ func ToLicense(rootdir string, deps []Dep) depot.LicenseStructure {
 	for _, d := range deps {
 
 		name := fmt.Sprintf("%s %s", d.Name, d.Version)
+		if len(d.Groups) > 0 {
+			name = fmt.Sprintf("%s [%s]", name, strings.Join(d.Groups, ","))
+		}
 		if d.Indirect {
 			name = name + " //indirect"
 		}
@@ -64,29 +112,124 @@ type Dep struct {
 	Version  string          `json:"v"`
 	Indirect bool            `json:"-"`
 	License  []string        `json:"l"`
+
+	// Provenance is the license provider the licenses were looked up with, or the lockfile stating them, empty for
+	// licenses read locally otherwise
+	Provenance string `json:"p,omitempty"`
+	// Warnings are disagreements about the licenses found by cross-checking providers, reported by lint
+	Warnings []string `json:"w,omitempty"`
+	// Errors are the failures of the providers asked for licenses no provider knew, reported by lint. Deps
+	// looked up with errors are not cached, they are looked up again on the next run.
+	Errors []string `json:"-"`
+
+	// Groups are the optional dependency groups, e.g. python extras, the dependency is only needed by
+	Groups []string `json:"-"`
+
+	// Issues are problems found with the dependency declaration, such as unpinned versions, reported by lint
+	Issues []string `json:"-"`
+
+	// deferred is the lookup of the licenses, while the deps of the files read are collected
+	deferred *deferred
 }
 
 func (d Dep) Key() string {
 	return DepKey(d.Type, d.Name, d.Version)
 }
 func DepKey(_type depsdev.DepType, name string, version string) string {
+	name, version = Canonical(_type, name, version)
 	return fmt.Sprintf("%s|%s|%s", _type, name, version)
 }
 
+// Canonical returns the form of a name and version that the ecosystem considers identical,
+// e.g. Django 4.2.0-RC1 and django 4.2.0rc1 are the same pypi package per PEP 503 and PEP 440
+func Canonical(_type depsdev.DepType, name string, version string) (string, string) {
+	switch _type {
+	case depsdev.PYPI:
+		return pypi.Normalize(name), pypi.NormalizeVersion(version)
+	case depsdev.NUGET:
+		return strings.ToLower(name), nuget.NormalizeVersion(version)
+	case depsdev.COMPOSER:
+		return strings.ToLower(name), version
+	}
+	return name, version
+}
+
+// FromFile reads the deps of a file, looking up the licenses it does not state, unless it was prefetched
 func (pro *Processor) FromFile(path string) ([]Dep, error) {
+	if r, found := pro.prefetched(path); found {
+		return r.deps, r.err
+	}
+	return pro.resolve(pro.fromFile(path))
+}
+
+func (pro *Processor) fromFile(path string) ([]Dep, error) {
 	filename := filepath.Base(path)
 
+	if jar.IsArchive(filename) {
+		return pro.fromArchive(path)
+	}
+
+	if nuget.IsProject(filename) {
+		return pro.fromMSBuildProject(path)
+	}
+
+	// A directory given to us is a container root filesystem or a python environment
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		if rootfs.IsRootFS(os.DirFS(path)) {
+			return pro.FromRootFS(path)
+		}
+		return pro.fromPythonEnv(path)
+	}
+
+	if rootfs.IsImage(filename) {
+		return pro.FromImage(path)
+	}
+
+	if sbom.IsSBOM(filename) {
+		return pro.fromSBOM(path)
+	}
+
+	if actions.IsWorkflow(path) || actions.IsAction(filename) {
+		return pro.fromWorkflow(path)
+	}
+
 	switch strings.ToLower(filename) {
 	case "package-lock.json":
-		return pro.From(path, depsdev.NPM)
+		return pro.from(path, depsdev.NPM)
 	case "go.mod":
-		return pro.From(path, depsdev.GO)
+		return pro.from(path, depsdev.GO)
 	case "pom.xml":
-		return pro.From(path, depsdev.MAVEN)
+		return pro.from(path, depsdev.MAVEN)
 	case "cargo.lock":
-		return pro.From(path, depsdev.CARGO)
+		return pro.from(path, depsdev.CARGO)
 	case "requirements.txt":
-		return pro.From(path, depsdev.PYPI)
+		return pro.from(path, depsdev.PYPI)
+	case "poetry.lock", "pipfile.lock", "pdm.lock", "uv.lock":
+		return pro.fromPythonLock(path)
+	case "pyproject.toml":
+		return pro.fromPyProject(path)
+	case "packages.lock.json":
+		return pro.fromNuGetLock(path)
+	case "packages.config":
+		return pro.fromPackagesConfig(path)
+	case "gemfile.lock":
+		return pro.fromGemfileLock(path)
+	case "composer.lock":
+		return pro.FromComposerLock(path)
+	case "package.resolved":
+		return pro.fromSwiftResolved(path)
+	case "pubspec.lock":
+		return pro.fromPubspecLock(path)
+	case "mix.lock":
+		return pro.fromMixLock(path)
+	case "vcpkg.json":
+		return pro.FromVcpkg(path)
+	case "conan.lock":
+		return pro.FromConanLock(path)
+	case ".terraform.lock.hcl":
+		return pro.fromTerraformLock(path)
+	case "chart.lock":
+		return pro.fromHelmLock(path)
 	}
 
 	return nil, fmt.Errorf("could not find any dep type associated with file name %s", filename)
@@ -94,24 +237,32 @@ func (pro *Processor) FromFile(path string) ([]Dep, error) {
 }
 
 func (pro *Processor) From(file string, _type depsdev.DepType) ([]Dep, error) {
+	return pro.resolve(pro.from(file, _type))
+}
+
+func (pro *Processor) from(file string, _type depsdev.DepType) ([]Dep, error) {
 	switch _type {
 	case depsdev.GO:
-		return pro.FromGO(file)
+		return pro.fromGO(file)
 	case depsdev.NPM:
-		return pro.FromNPM(file)
+		return pro.fromNPM(file)
 	case depsdev.MAVEN:
-		return pro.FromMaven(file)
+		return pro.fromMaven(file)
 	case depsdev.CARGO:
-		return pro.FromCargo(file)
+		return pro.fromCargo(file)
 	case depsdev.PYPI:
-		return pro.FromPypi(file)
+		return pro.fromPypi(file)
 	}
 
 	return nil, fmt.Errorf("type %s does not exist", _type)
 
 }
 
-func (pro *Processor) FromNPM(path string) (deps []Dep, err error) {
+func (pro *Processor) FromNPM(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromNPM(path))
+}
+
+func (pro *Processor) fromNPM(path string) (deps []Dep, err error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -148,7 +299,8 @@ func (pro *Processor) FromNPM(path string) (deps []Dep, err error) {
 		}
 	}), lockfile.Dependencies)
 
-	for name, d := range merged {
+	for _, name := range slicez.Sort(mapz.Keys(merged)) {
+		d := merged[name]
 		if name == "" { // self...
 			continue
 		}
@@ -158,15 +310,13 @@ func (pro *Processor) FromNPM(path string) (deps []Dep, err error) {
 		}
 		name = slicez.Nth(strings.Split(name, "node_modules/"), -1)
 
-		l, _ := pro.LicensesOf(depsdev.NPM, name, d.Version)
-
 		deps = append(deps, Dep{
 			Context:  path,
 			Type:     depsdev.NPM,
 			Name:     name,
 			Version:  d.Version,
 			Indirect: !direct.Exists(name),
-			License:  l,
+			deferred: &deferred{dir: filepath.Dir(path)},
 		})
 	}
 	return slicez.UniqBy(deps, func(a Dep) string {
@@ -174,7 +324,11 @@ func (pro *Processor) FromNPM(path string) (deps []Dep, err error) {
 	}), nil
 }
 
-func (pro *Processor) FromCargo(lockFilePath string) (deps []Dep, err error) {
+func (pro *Processor) FromCargo(lockFilePath string) ([]Dep, error) {
+	return pro.resolve(pro.fromCargo(lockFilePath))
+}
+
+func (pro *Processor) fromCargo(lockFilePath string) (deps []Dep, err error) {
 
 	b, err := os.ReadFile(lockFilePath)
 	if err != nil {
@@ -186,32 +340,109 @@ func (pro *Processor) FromCargo(lockFilePath string) (deps []Dep, err error) {
 		return nil, err
 	}
 
-	p, _ := slicez.Find(lockfile.Packages, func(pkg cargo.Pkg) bool {
-		return pkg.Source == ""
+	// Path sourced packages are our own, workspace members or local path dependencies.
+	// Directness is decided by the workspace members, falling back on all local packages
+	// if there is no Cargo.toml to tell us which they are.
+	roots := slicez.Filter(lockfile.Packages, func(pkg cargo.Pkg) bool {
+		return pkg.Local()
+	})
+	members := cargoMembers(filepath.Dir(lockFilePath))
+	if len(members.Keys()) > 0 {
+		roots = slicez.Filter(roots, func(pkg cargo.Pkg) bool {
+			return members.Exists(pkg.Name)
+		})
+	}
+	direct := slicez.FlatMap(roots, func(pkg cargo.Pkg) []cargo.Dependency {
+		return slicez.Map(pkg.Dependencies, cargo.ParseDependency)
 	})
-	direct := set.From(p.Dependencies...)
 
 	for _, d := range lockfile.Packages {
 
-		if d.Name == p.Name {
+		if d.Local() {
 			continue
 		}
 
-		l, _ := pro.LicensesOf(depsdev.CARGO, d.Name, d.Version)
-
-		deps = append(deps, Dep{
-			Context:  lockFilePath,
-			Type:     depsdev.CARGO,
-			Name:     d.Name,
-			Version:  d.Version,
-			Indirect: !direct.Exists(d.Name),
-			License:  l,
-		})
+		// deps.dev only knows crates.io, forks in git and private registries may
+		// share names with crates there, so we only trust the crate itself for those
+		dep := Dep{
+			Context: lockFilePath,
+			Type:    depsdev.CARGO,
+			Name:    d.Name,
+			Version: d.Version,
+			Indirect: !slicez.ContainsFunc(direct, func(dep cargo.Dependency) bool {
+				return dep.Matches(d)
+			}),
+		}
+		switch d.Kind() {
+		case cargo.SourceCratesIO:
+			dep.deferred = &deferred{dir: filepath.Dir(lockFilePath)}
+		default:
+			dep.License = cargoLocalLicense(d)
+		}
+		deps = append(deps, dep)
 	}
 	return deps, nil
 }
 
-func (pro *Processor) FromGO(path string) (deps []Dep, err error) {
+// cargoMembers returns the package names of the crates in the workspace, or single package, described by dir/Cargo.toml
+func cargoMembers(dir string) set.Set[string] {
+	members := set.New[string]()
+
+	manifest, err := cargo.ReadManifest(filepath.Join(dir, "Cargo.toml"))
+	if err != nil {
+		return members
+	}
+	if manifest.Package.Name != "" {
+		members.Add(manifest.Package.Name)
+	}
+
+	excluded := set.From(slicez.Map(manifest.Workspace.Exclude, func(a string) string {
+		return filepath.Join(dir, a)
+	})...)
+
+	for _, pattern := range manifest.Workspace.Members {
+		paths, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			log.WithError(err).Warnf("cargo; bad workspace member pattern %s", pattern)
+			continue
+		}
+		for _, path := range paths {
+			if excluded.Exists(path) {
+				continue
+			}
+			m, err := cargo.ReadManifest(filepath.Join(path, "Cargo.toml"))
+			if err != nil {
+				continue
+			}
+			if m.Package.Name != "" {
+				members.Add(m.Package.Name)
+			}
+		}
+	}
+	return members
+}
+
+// cargoLocalLicense reads the license of a package from its Cargo.toml in the local cargo home
+func cargoLocalLicense(pkg cargo.Pkg) []string {
+	manifest, root, err := cargo.FindManifest(cargo.Home(), pkg)
+	if err != nil {
+		log.WithError(err).Warnf("cargo; could not find %s %s locally, fetch it with cargo to resolve its license", pkg.Name, pkg.Version)
+		return []string{"~unknown"}
+	}
+	license, err := cargo.LicenseOf(manifest, root)
+	if err != nil {
+		log.WithError(err).Warnf("cargo; could not read license of %s %s", pkg.Name, pkg.Version)
+		return []string{"~unknown"}
+	}
+	log.Infof("cargo; license of %s %s from %s", pkg.Name, pkg.Version, manifest)
+	return []string{license}
+}
+
+func (pro *Processor) FromGO(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromGO(path))
+}
+
+func (pro *Processor) fromGO(path string) (deps []Dep, err error) {
 
 	//TODO recurese down indirect deps if wanted.
 
@@ -224,22 +455,37 @@ func (pro *Processor) FromGO(path string) (deps []Dep, err error) {
 		return nil, err
 	}
 
-	for _, r := range file.Require {
-		l, _ := pro.LicensesOf(depsdev.GO, r.Mod.Path, r.Mod.Version)
+	// modules replaced by a local directory are not published, their license is in the directory
+	local := map[string]string{}
+	for _, r := range file.Replace {
+		if r.New.Version == "" {
+			local[r.Old.Path] = filepath.Join(filepath.Dir(path), r.New.Path)
+		}
+	}
 
-		deps = append(deps, Dep{
+	for _, r := range file.Require {
+		dep := Dep{
 			Context:  path,
 			Type:     depsdev.GO,
 			Name:     r.Mod.Path,
 			Version:  r.Mod.Version,
 			Indirect: r.Indirect,
-			License:  l,
-		})
+		}
+		if dir, ok := local[r.Mod.Path]; ok {
+			dep.License = dirLicense(depsdev.GO, r.Mod.Path, r.Mod.Version, dir)
+		} else {
+			dep.deferred = &deferred{dir: filepath.Dir(path)}
+		}
+		deps = append(deps, dep)
 	}
 	return deps, nil
 }
 
-func (pro *Processor) FromMaven(path string) (deps []Dep, err error) {
+func (pro *Processor) FromMaven(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromMaven(path))
+}
+
+func (pro *Processor) fromMaven(path string) (deps []Dep, err error) {
 
 	b, err := os.ReadFile(path)
 	if err != nil {
@@ -267,118 +513,1194 @@ func (pro *Processor) FromMaven(path string) (deps []Dep, err error) {
 
 		name := fmt.Sprintf("%s:%s", d.GroupID, d.ArtifactID)
 
-		l, _ := pro.LicensesOf(depsdev.MAVEN, name, d.Version)
-
 		deps = append(deps, Dep{
 			Context:  path,
 			Type:     depsdev.MAVEN,
 			Name:     name,
 			Version:  d.Version,
 			Indirect: false,
-			License:  l,
+			deferred: &deferred{dir: filepath.Dir(path)},
 		})
 	}
 	return deps, nil
 }
-func (pro *Processor) FromPypi(path string) (deps []Dep, err error) {
 
+// FromArchive reads the maven artifacts embedded in a jar, war or ear. Artifacts in nested archives,
+// e.g. WEB-INF/lib or BOOT-INF/lib, are considered indirect.
+func (pro *Processor) FromArchive(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromArchive(path))
+}
+
+func (pro *Processor) fromArchive(path string) (deps []Dep, err error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts, err := jar.Scan(f, info.Size(), filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
 
-	rStripByKey := func(line string, key string) string {
-		if pos := strings.Index(line, key); pos >= 0 {
-			line = strings.TrimRightFunc((line)[:pos], unicode.IsSpace)
+	for _, a := range artifacts {
+		// The archive may know better than deps.dev, e.g. for artifacts never published to maven central
+		fallback := func() []string {
+			declared := a.SPDX()
+			if len(declared) == 0 {
+				return []string{"~unknown"}
+			}
+			log.Infof("jar; using license declared in %s for %s %s", a.Archive, a.Name(), a.Version)
+			return declared
 		}
-		return line
+
+		deps = append(deps, Dep{
+			Context:  path,
+			Type:     depsdev.MAVEN,
+			Name:     a.Name(),
+			Version:  a.Version,
+			Indirect: a.Nested,
+			deferred: &deferred{dir: filepath.Dir(path), fallback: fallback},
+		})
+	}
+	return slicez.UniqBy(deps, func(a Dep) string {
+		return a.Key()
+	}), nil
+}
+
+func (pro *Processor) FromPypi(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromPypi(path))
+}
+
+func (pro *Processor) fromPypi(path string) (deps []Dep, err error) {
+
+	reqs, err := pypi.ReadRequirements(path)
+	if err != nil {
+		return nil, err
 	}
+	return pro.fromRequirements(path, reqs)
+}
+
+// FromPyProject reads the dependencies declared in pyproject.toml. Projects with a lockfile are
+// read from the lockfile instead, pyproject.toml only holds the ranges the lockfile was resolved from.
+func (pro *Processor) FromPyProject(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromPyProject(path))
+}
 
-	removeExtras := func(line string) string {
-		startIndex := strings.Index(line, "[")
-		endIndex := strings.Index(line, "]") + 1
-		if startIndex != -1 && endIndex != -1 {
-			line = line[:startIndex] + line[endIndex:]
+func (pro *Processor) fromPyProject(path string) (deps []Dep, err error) {
+	for _, lockfile := range []string{"poetry.lock", "pdm.lock", "uv.lock", "Pipfile.lock"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), lockfile)); err == nil {
+			log.Infof("pypi; ignoring %s in favour of %s", path, lockfile)
+			return nil, nil
 		}
-		return line
 	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.ReplaceAll(line, " ", "")
-		line = strings.ReplaceAll(line, `\`, "")
-		line = removeExtras(line)
-		line = rStripByKey(line, "#")
-		line = rStripByKey(line, ";")
-		line = rStripByKey(line, "--")
-		s := strings.Split(line, "==")
-		if len(s) != 2 {
-			continue
+	project, err := pypi.ReadPyProject(path)
+	if err != nil {
+		return nil, err
+	}
+	reqs := slicez.Map(project.Requirements(), func(r pypi.Requirement) pypi.Requirement {
+		r.File = path
+		return r
+	})
+	return pro.fromRequirements(path, pypi.Requirements{Requirements: reqs})
+}
+
+func (pro *Processor) fromRequirements(path string, reqs pypi.Requirements) (deps []Dep, err error) {
+
+	var installed map[string]string
+	if pro.pythonEnv != "" {
+		installed, err = pypi.Installed(pro.pythonEnv)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		name := s[0]
-		version := s[1]
+	for _, r := range reqs.Requirements {
 
-		licence, _ := pro.LicensesOf(depsdev.PYPI, name, version)
-		deps = append(deps, Dep{
-			Context:  path,
+		dep := Dep{
+			Context:  r.File,
 			Type:     depsdev.PYPI,
-			Name:     name,
-			Version:  version,
+			Name:     pypi.Normalize(r.Name),
 			Indirect: false,
-			License:  licence,
-		})
+		}
+		if r.Optional != "" {
+			dep.Groups = []string{r.Optional}
+		}
+
+		if r.URL != "" {
+			// Local directories and archives are our own code
+			if r.Local() {
+				log.Infof("pypi; ignoring local requirement %s in %s", r.URL, path)
+				continue
+			}
+			// There is no registry to ask about a url, it has to be addressed in .depot.yml
+			if dep.Name == "" {
+				dep.Name = r.URL
+			}
+			dep.Version = r.URL
+			dep.License = []string{"~unknown"}
+			deps = append(deps, dep)
+			continue
+		}
+
+		version, pinned := r.Version()
+		if !pinned {
+			version, pinned = reqs.Constraint(r.Name)
+		}
+		if !pinned && installed != nil {
+			version, pinned = installed[pypi.Normalize(r.Name)]
+			if pinned {
+				dep.Issues = append(dep.Issues, fmt.Sprintf("requirement %s%s is not pinned, resolved to the installed %s", r.Name, r.Specifier, version))
+			}
+		}
+		if !pinned {
+			dep.Version = r.Specifier
+			if dep.Version == "" {
+				dep.Version = "*"
+			}
+			// there is no version to look up the licenses of, being unpinned is an issue failing only lint --strict
+			dep.Issues = append(dep.Issues, fmt.Sprintf("requirement %s%s is not pinned to a version in %s", r.Name, r.Specifier, location(r)))
+			deps = append(deps, dep)
+			continue
+		}
+
+		dep.Version = pypi.NormalizeVersion(version)
+		dep.deferred = &deferred{dir: filepath.Dir(path)}
+		deps = append(deps, dep)
 	}
-	if err := scanner.Err(); err != nil {
+
+	return deps, nil
+}
+
+func location(r pypi.Requirement) string {
+	if r.Line == 0 {
+		return r.File
+	}
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// FromPythonEnv reads the packages installed in a python environment, a virtualenv or site-packages directory.
+// Licenses are taken from the installed package metadata, deps.dev is only asked about packages without any.
+func (pro *Processor) FromPythonEnv(env string) ([]Dep, error) {
+	return pro.resolve(pro.fromPythonEnv(env))
+}
+
+func (pro *Processor) fromPythonEnv(env string) (deps []Dep, err error) {
+	dists, err := pypi.Distributions(env)
+	if err != nil {
 		return nil, err
 	}
 
+	// pip marks what was asked for with REQUESTED, other installers leave directness to the dependency graph
+	requested := slicez.Filter(dists, func(d pypi.Distribution) bool {
+		return d.Requested
+	})
+	required := set.From(slicez.FlatMap(dists, func(d pypi.Distribution) []string {
+		return d.Requires
+	})...)
+	isDirect := func(d pypi.Distribution) bool {
+		if len(requested) > 0 {
+			return d.Requested
+		}
+		return !required.Exists(d.Name)
+	}
+
+	for _, d := range dists {
+		// Ignore the project itself, installed from its directory
+		if d.Local {
+			continue
+		}
+
+		dep := Dep{
+			Context:  env,
+			Type:     depsdev.PYPI,
+			Name:     d.Name,
+			Version:  d.Version,
+			Indirect: !isDirect(d),
+			License:  d.SPDX(),
+		}
+		if len(dep.License) > 0 {
+			log.Infof("pypi; license of %s %s from %s", d.Name, d.Version, d.Path)
+		} else {
+			dep.deferred = &deferred{dir: filepath.Dir(env)}
+		}
+		deps = append(deps, dep)
+	}
 	return deps, nil
 }
 
-func (pro *Processor) LicensesOf(depType depsdev.DepType, name string, version string) ([]string, error) {
-	key := DepKey(depType, name, version)
+// FromPythonLock reads the python lockfiles poetry.lock, Pipfile.lock, pdm.lock and uv.lock
+func (pro *Processor) FromPythonLock(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromPythonLock(path))
+}
 
-	if pro.cache != nil {
-		dep, found := pro.cache.Get(key)
+func (pro *Processor) fromPythonLock(path string) (deps []Dep, err error) {
+	pkgs, err := pypi.ReadLockfile(path)
+	if err != nil {
+		return nil, err
+	}
 
-		if found {
-			log.Infof("deps.dev; licence cache hit for %s", dep.Key())
-			return dep.License, nil
+	for _, p := range pkgs {
+		// Ignore the project itself and path dependencies
+		if p.Local {
+			continue
+		}
+		// Ignore dev deps
+		if p.Dev {
+			continue
+		}
+
+		dep := Dep{
+			Context:  path,
+			Type:     depsdev.PYPI,
+			Name:     p.Name,
+			Version:  p.Version,
+			Indirect: !p.Direct,
+			Groups:   p.Extras,
+		}
+		// Packages locked to git or urls may be forks, deps.dev only knows the index
+		if p.Source != "" {
+			log.Infof("pypi; %s %s is locked to %s, its license has to be addressed in .depot.yml", p.Name, p.Version, p.Source)
+			dep.License = []string{"~unknown"}
+		} else {
+			dep.deferred = &deferred{dir: filepath.Dir(path)}
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// FromNuGetLock reads packages.lock.json. A package is direct if it is referenced directly for any target framework.
+func (pro *Processor) FromNuGetLock(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromNuGetLock(path))
+}
+
+func (pro *Processor) fromNuGetLock(path string) (deps []Dep, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lockfile nuget.LockFile
+	err = json.Unmarshal(b, &lockfile)
+	if err != nil {
+		return nil, err
+	}
+
+	type locked struct {
+		name    string
+		version string
+		direct  bool
+	}
+	packages := map[string]*locked{}
+
+	for _, framework := range slicez.Sort(mapz.Keys(lockfile.Dependencies)) {
+		for name, d := range lockfile.Dependencies[framework] {
+			// Other projects in the solution
+			if d.Type == "Project" {
+				continue
+			}
+			key := DepKey(depsdev.NUGET, name, d.Resolved)
+			if packages[key] == nil {
+				packages[key] = &locked{name: name, version: d.Resolved}
+			}
+			packages[key].direct = packages[key].direct || d.Direct()
 		}
 	}
 
-	log.Infof("deps.dev; requesting %s", DepKey(depType, name, version))
-	v, err := depsdev.New().Version(depType, name, version)
+	for _, key := range slicez.Sort(mapz.Keys(packages)) {
+		p := packages[key]
+		deps = append(deps, Dep{
+			Context:  path,
+			Type:     depsdev.NUGET,
+			Name:     p.name,
+			Version:  p.version,
+			Indirect: !p.direct,
+			deferred: &deferred{dir: filepath.Dir(path)},
+		})
+	}
+	return deps, nil
+}
+
+// FromMSBuildProject reads the PackageReference items of a .csproj, .fsproj or .vbproj, with versions from
+// Directory.Packages.props for central package management. Projects with a packages.lock.json are read from
+// the lockfile instead, which also holds the transitive dependencies.
+func (pro *Processor) FromMSBuildProject(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromMSBuildProject(path))
+}
 
-	if err != nil && err.Error() == "http status 404" {
-		err = nil
+func (pro *Processor) fromMSBuildProject(path string) (deps []Dep, err error) {
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), "packages.lock.json")); err == nil {
+		log.Infof("nuget; ignoring %s in favour of packages.lock.json", path)
+		return nil, nil
 	}
+
+	project, err := nuget.ReadProject(path)
 	if err != nil {
-		log.WithError(err).Fatalf("could not retrive dep %s %s %s", depType, name, version)
+		return nil, err
 	}
 
-	license := slicez.Map(v.Licenses, func(a string) string {
-		if a == "non-standard" {
-			a = "~non-standard"
+	central := map[string]string{}
+	if props, found := nuget.FindPackagesProps(path); found {
+		p, err := nuget.ReadProject(props)
+		if err != nil {
+			return nil, err
+		}
+		managed, declared := project.CentrallyManaged()
+		if !declared {
+			managed, _ = p.CentrallyManaged()
+		}
+		if managed {
+			for _, v := range p.Versions() {
+				central[strings.ToLower(v.Name())] = v.GetVersion()
+			}
+		}
+	}
+
+	for _, ref := range project.References() {
+		// Update items change references made elsewhere
+		if ref.Include == "" {
+			continue
+		}
+		// Ignore analyzers and build tools that do not flow to consumers
+		if ref.DevelopmentOnly() {
+			continue
+		}
+
+		dep := Dep{
+			Context:  path,
+			Type:     depsdev.NUGET,
+			Name:     ref.Include,
+			Indirect: false,
+		}
+
+		requested := ref.GetVersion()
+		if requested == "" {
+			requested = central[strings.ToLower(ref.Include)]
+		}
+		version, exact := nuget.ExactVersion(requested)
+		if !exact {
+			dep.Version = requested
+			if dep.Version == "" {
+				dep.Version = "*"
+			}
+			dep.Issues = append(dep.Issues, fmt.Sprintf("package reference %s %s is not an exact version in %s", ref.Include, requested, path))
+			deps = append(deps, dep)
+			continue
 		}
-		return a
-	})
 
-	if len(license) == 0 {
-		license = []string{"~unknown"}
+		dep.Version = version
+		dep.deferred = &deferred{dir: filepath.Dir(path)}
+		deps = append(deps, dep)
 	}
+	return deps, nil
+}
 
-	if pro.cache != nil {
-		pro.cache.Put(Dep{
-			Type:    depType,
-			Name:    name,
-			Version: version,
-			License: license,
-		})
+// FromPackagesConfig reads the legacy packages.config, which lists direct and transitive packages alike
+func (pro *Processor) FromPackagesConfig(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromPackagesConfig(path))
+}
+
+func (pro *Processor) fromPackagesConfig(path string) (deps []Dep, err error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var config nuget.PackagesConfig
+	err = xml.Unmarshal(b, &config)
+	if err != nil {
+		return nil, err
 	}
 
-	return license, err
+	for _, p := range config.Packages {
+		// Ignore dev deps
+		if p.DevelopmentDependency {
+			continue
+		}
+
+		deps = append(deps, Dep{
+			Context:  path,
+			Type:     depsdev.NUGET,
+			Name:     p.ID,
+			Version:  p.Version,
+			Indirect: false,
+			deferred: &deferred{dir: filepath.Dir(path)},
+		})
+	}
+	return slicez.UniqBy(deps, func(a Dep) string {
+		return a.Key()
+	}), nil
+}
+
+// FromGemfileLock reads a bundler Gemfile.lock. Gems from rubygems.org are looked up with the rubygems
+// license provider, gems from other sources, or unknown to it, by the gemspec in the installed bundle.
+func (pro *Processor) FromGemfileLock(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromGemfileLock(path))
+}
+
+func (pro *Processor) fromGemfileLock(path string) (deps []Dep, err error) {
+	lockfile, err := gem.ReadLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	direct := set.From(lockfile.Dependencies...)
+	bundlePaths := gem.BundlePaths(filepath.Dir(path))
+
+	for _, source := range lockfile.Sources {
+		// Ignore our own gems
+		if source.Type == "PATH" {
+			continue
+		}
+
+		// Platform specific gems are listed once per platform, the plain ruby one is preferred for installed gemspecs.
+		// The dep, as its lookup, is the version whatever the platform.
+		specs := slicez.SortFunc(source.Specs, func(a, b gem.Spec) bool {
+			return a.Platform < b.Platform
+		})
+		specs = slicez.UniqBy(specs, func(s gem.Spec) string {
+			return s.Name + " " + s.Version
+		})
+
+		for _, spec := range specs {
+			dep := Dep{
+				Context:  path,
+				Type:     depsdev.RUBYGEMS,
+				Name:     spec.Name,
+				Version:  spec.Version,
+				Indirect: !direct.Exists(spec.Name),
+			}
+			fallback := func() []string {
+				return gemLocalLicense(bundlePaths, spec, source.Revision)
+			}
+			if source.RubyGemsOrg() {
+				dep.deferred = &deferred{dir: filepath.Dir(path), fallback: fallback}
+			} else {
+				dep.License = fallback()
+			}
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+// gemLocalLicense reads the license of a gem from its gemspec in the installed bundle
+func gemLocalLicense(bundlePaths []string, spec gem.Spec, revision string) []string {
+	gemspec, found := gem.FindGemspec(bundlePaths, spec, revision)
+	if !found {
+		log.Warnf("gem; could not find %s %s installed, run bundle install to resolve its license", spec.Name, spec.FullVersion())
+		return []string{"~unknown"}
+	}
+	licenses, err := gem.Licenses(gemspec)
+	if err != nil || len(licenses) == 0 {
+		log.WithError(err).Warnf("gem; could not read license of %s %s from %s", spec.Name, spec.FullVersion(), gemspec)
+		return []string{"~unknown"}
+	}
+	log.Infof("gem; license of %s %s from %s", spec.Name, spec.FullVersion(), gemspec)
+	return licenses
+}
+
+// FromComposerLock reads composer.lock. The lockfile carries the licenses of every package, so no lookup is made,
+// the lockfile being the provenance of the licenses, and composer.json next to it tells which packages are required
+// directly. packages-dev are left out, as dev deps of every other ecosystem.
+func (pro *Processor) FromComposerLock(path string) (deps []Dep, err error) {
+	lockfile, err := composer.ReadLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := composer.ReadManifest(filepath.Join(filepath.Dir(path), "composer.json"))
+	if err != nil {
+		log.WithError(err).Warnf("composer; could not read composer.json next to %s, all packages are considered indirect", path)
+	}
+
+	for _, p := range lockfile.All() {
+		// Ignore dev deps
+		if p.Dev {
+			continue
+		}
+		// Ignore packages within the project itself
+		if p.Local() {
+			continue
+		}
+
+		dep := Dep{
+			Context:  path,
+			Type:     depsdev.COMPOSER,
+			Name:     p.Name,
+			Version:  p.Version,
+			Indirect: !manifest.Direct(p.Name),
+		}
+		// Several licenses of a package are a choice between them
+		switch len(p.License) {
+		case 0:
+			dep.License = []string{"~unknown"}
+		case 1:
+			dep.License = p.License
+		default:
+			dep.License = []string{"(" + strings.Join(p.License, " OR ") + ")"}
+		}
+		if len(p.License) > 0 {
+			dep.Provenance = filepath.Base(path)
+			log.Infof("composer; license of %s %s from %s", p.Name, p.Version, path)
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// FromSwiftResolved reads Package.resolved of swift package manager or xcode. Packages are named by their repository,
+// which licenses are looked up by, falling back on the checkout in .build.
+func (pro *Processor) FromSwiftResolved(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromSwiftResolved(path))
+}
+
+func (pro *Processor) fromSwiftResolved(path string) (deps []Dep, err error) {
+	pins, err := swift.ReadResolved(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	direct, err := swift.Dependencies(filepath.Join(dir, "Package.swift"))
+	known := err == nil
+	if !known {
+		// xcode keeps Package.resolved within the project and declares packages in project.pbxproj
+		log.Infof("swift; no Package.swift next to %s, all packages are considered direct", path)
+	}
+
+	for _, p := range pins {
+		// Ignore packages within the project itself
+		if p.Local() {
+			continue
+		}
+
+		fallback := func() []string {
+			return dirLicense(depsdev.SWIFT, p.Name(), p.GetVersion(), p.Checkout(dir))
+		}
+
+		deps = append(deps, Dep{
+			Context: path,
+			Type:    depsdev.SWIFT,
+			Name:    p.Name(),
+			Version: p.GetVersion(),
+			Indirect: known && !slicez.ContainsFunc(direct, func(name string) bool {
+				return strings.EqualFold(name, p.Name())
+			}),
+			deferred: &deferred{dir: dir, fallback: fallback},
+		})
+	}
+	return deps, nil
+}
+
+// FromPubspecLock reads a dart or flutter pubspec.lock. Packages from pub.dev are looked up there,
+// others, or those pub.dev does not know the license of, in the pub cache.
+func (pro *Processor) FromPubspecLock(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromPubspecLock(path))
+}
+
+func (pro *Processor) fromPubspecLock(path string) (deps []Dep, err error) {
+	lockfile, err := pub.ReadLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := pub.Cache()
+	for _, name := range slicez.Sort(mapz.Keys(lockfile.Packages)) {
+		p := lockfile.Packages[name]
+
+		// Ignore the project itself, path dependencies and the sdk
+		if p.Local() {
+			continue
+		}
+		// Ignore dev deps, pubspec.lock does not tell which transitive packages only they need
+		if p.Dev() {
+			continue
+		}
+
+		dep := Dep{
+			Context:  path,
+			Type:     depsdev.PUB,
+			Name:     name,
+			Version:  p.Version,
+			Indirect: !p.Direct(),
+		}
+		fallback := func() []string {
+			pkgDir, _ := pub.FindPackage(cache, name, p)
+			return dirLicense(depsdev.PUB, name, p.Version, pkgDir)
+		}
+		if p.PubDev() {
+			dep.deferred = &deferred{dir: filepath.Dir(path), fallback: fallback}
+		} else {
+			dep.License = fallback()
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// FromMixLock reads an elixir mix.lock, with the deps declared by mix.exs next to it being direct.
+// Packages from hex.pm are looked up there, others, or those hex.pm does not know, in the fetched deps/.
+func (pro *Processor) FromMixLock(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromMixLock(path))
+}
+
+func (pro *Processor) fromMixLock(path string) (deps []Dep, err error) {
+	locks, err := mix.ReadLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	direct, dev, err := mix.Dependencies(dir)
+	if err != nil {
+		log.WithError(err).Warnf("mix; could not read mix.exs next to %s, all packages are considered indirect", path)
+	}
+
+	for _, lock := range locks {
+		// Ignore path deps, e.g. umbrella apps
+		if lock.Source == "path" {
+			continue
+		}
+		// Ignore dev deps
+		if slicez.Contains(dev, lock.App) {
+			continue
+		}
+
+		name := lock.App
+		if lock.Package != "" {
+			name = lock.Package
+		}
+
+		dep := Dep{
+			Context:  path,
+			Type:     depsdev.HEX,
+			Name:     name,
+			Version:  lock.Version,
+			Indirect: !slicez.Contains(direct, lock.App),
+		}
+		fallback := func() []string {
+			return mixLocalLicense(filepath.Join(dir, "deps", lock.App), name, lock.Version)
+		}
+		if lock.HexPM() {
+			dep.deferred = &deferred{dir: dir, fallback: fallback}
+		} else {
+			dep.License = fallback()
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// mixLocalLicense reads the license of a fetched dep from the hex metadata, or the license files, in deps/<app>
+func mixLocalLicense(depDir string, name string, version string) []string {
+	licenses, err := mix.Licenses(depDir)
+	if err == nil && len(licenses) > 0 {
+		log.Infof("mix; license of %s %s from %s", name, version, depDir)
+		return licenses
+	}
+	return dirLicense(depsdev.HEX, name, version, depDir)
+}
+
+// FromVcpkg reads the vcpkg.json of a project in manifest mode. The ports installed into vcpkg_installed are the
+// dependencies, or if not installed, the direct dependencies as resolved against the baseline in the ports tree at
+// $VCPKG_ROOT. Licenses are read from the SBOMs vcpkg writes on install and the port manifests in the ports tree.
+func (pro *Processor) FromVcpkg(path string) (deps []Dep, err error) {
+	manifest, err := vcpkg.ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	root := vcpkg.Root()
+
+	// Host dependencies are tools used while building, as dev deps they are ignored
+	host := set.New[string]()
+	direct := set.New[string]()
+	for _, d := range manifest.Dependencies {
+		if d.Host {
+			host.Add(d.Name)
+			continue
+		}
+		direct.Add(d.Name)
+	}
+
+	pkgs, err := vcpkg.Installed(dir)
+	if err != nil {
+		log.Infof("vcpkg; nothing installed for %s, resolving its dependencies against the baseline", path)
+		pkgs = vcpkgResolve(manifest, dir, root)
+	}
+
+	for _, p := range pkgs {
+		if host.Exists(p.Name) {
+			continue
+		}
+
+		dep := Dep{
+			Context:  path,
+			Type:     depsdev.VCPKG,
+			Name:     p.Name,
+			Version:  p.Version,
+			Indirect: !direct.Exists(p.Name),
+			License:  vcpkgLicense(dir, root, p),
+		}
+		if p.Version == "" {
+			dep.Issues = append(dep.Issues, "version could not be resolved, run vcpkg install or set VCPKG_ROOT to a ports tree at the baseline")
+		}
+		deps = append(deps, dep)
+	}
+	return slicez.UniqBy(deps, func(a Dep) string {
+		return a.Key()
+	}), nil
+}
+
+func vcpkgResolve(manifest vcpkg.Manifest, dir string, root string) []vcpkg.Package {
+	var baseline map[string]string
+	if root != "" {
+		b, err := vcpkg.Baseline(root)
+		if err != nil {
+			log.WithError(err).Warnf("vcpkg; could not read the baseline of %s", root)
+		}
+		baseline = b
+
+		head, err := vcpkg.Head(root)
+		if want := manifest.Baseline(dir); err == nil && want != "" && head != want {
+			log.Warnf("vcpkg; %s is at %s, not at the baseline %s, versions may differ from what vcpkg installs", root, head, want)
+		}
+	}
+	versions := manifest.Resolve(baseline)
+
+	var pkgs []vcpkg.Package
+	for _, d := range manifest.Dependencies {
+		if d.Host {
+			continue
+		}
+		pkgs = append(pkgs, vcpkg.Package{Name: d.Name, Version: versions[d.Name]})
+	}
+	return pkgs
+}
+
+func vcpkgLicense(dir string, root string, p vcpkg.Package) []string {
+	if p.Triplet != "" {
+		if l, err := vcpkg.InstalledLicense(dir, p); err == nil {
+			log.Infof("vcpkg; license of %s %s from its installed sbom", p.Name, p.Version)
+			return []string{l}
+		}
+	}
+	if root != "" && p.Version != "" {
+		l, err := vcpkg.PortLicense(root, p.Name, p.Version)
+		if err == nil {
+			log.Infof("vcpkg; license of %s %s from the ports tree", p.Name, p.Version)
+			return []string{l}
+		}
+		log.WithError(err).Warnf("vcpkg; could not read the license of %s %s", p.Name, p.Version)
+	}
+	return []string{"~unknown"}
+}
+
+// FromConanLock reads conan.lock, of conan 1 or 2, with the requirements of conanfile.txt or conanfile.py next
+// to it being direct. Licenses are read from the recipes in the local conan cache.
+func (pro *Processor) FromConanLock(path string) (deps []Dep, err error) {
+	pkgs, err := conan.ReadLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	requirements, err := conan.Requirements(filepath.Dir(path))
+	if err != nil {
+		log.WithError(err).Warnf("conan; could not read the conanfile next to %s", path)
+	}
+
+	homes := conan.Home()
+	for _, p := range pkgs {
+		// Ignore build requirements, tools such as cmake
+		if p.Build {
+			continue
+		}
+
+		deps = append(deps, Dep{
+			Context:  path,
+			Type:     depsdev.CONAN,
+			Name:     p.Name,
+			Version:  p.Version,
+			Indirect: !p.Direct && !slicez.Contains(requirements, p.Name),
+			License:  conanLicense(homes, p.Reference),
+		})
+	}
+	return slicez.UniqBy(deps, func(a Dep) string {
+		return a.Key()
+	}), nil
+}
+
+func conanLicense(homes []string, r conan.Reference) []string {
+	recipe, found := conan.FindRecipe(homes, r)
+	if !found {
+		log.Warnf("conan; could not find the recipe of %s/%s in the conan cache, install it to resolve its license", r.Name, r.Version)
+		return []string{"~unknown"}
+	}
+	licenses, err := conan.License(recipe)
+	if err != nil || len(licenses) == 0 {
+		log.WithError(err).Warnf("conan; could not read the license of %s/%s from %s", r.Name, r.Version, recipe)
+		return []string{"~unknown"}
+	}
+	log.Infof("conan; license of %s/%s from %s", r.Name, r.Version, recipe)
+	return licenses
+}
+
+// FromRootFS reads the operating system packages installed in an extracted container root filesystem
+func (pro *Processor) FromRootFS(dir string) (deps []Dep, err error) {
+	return pro.fromOSPackages(dir, os.DirFS(dir))
+}
+
+// FromImage reads the operating system packages installed in an image saved with docker save
+func (pro *Processor) FromImage(path string) (deps []Dep, err error) {
+	fsys, err := rootfs.Image(path)
+	if err != nil {
+		return nil, err
+	}
+	return pro.fromOSPackages(path, fsys)
+}
+
+// fromOSPackages reads the dpkg, apk and rpm databases, licenses come from the package metadata
+func (pro *Processor) fromOSPackages(context string, fsys fs.FS) (deps []Dep, err error) {
+	pkgs, err := rootfs.Packages(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("could not read packages of %s: %w", context, err)
+	}
+
+	for _, p := range pkgs {
+		l := p.Licenses
+		if len(l) == 0 {
+			log.Warnf("%s; no license found for %s %s", p.Type, p.Name, p.Version)
+			l = []string{"~unknown"}
+		}
+
+		deps = append(deps, Dep{
+			Context:  context,
+			Type:     p.Type,
+			Name:     p.Name,
+			Version:  p.Version,
+			Indirect: p.Auto,
+			License:  l,
+		})
+	}
+	return slicez.UniqBy(deps, func(a Dep) string {
+		return a.Key()
+	}), nil
+}
+
+// FromTerraformLock reads the providers pinned in .terraform.lock.hcl, those in required_providers of the module
+// are direct. Licenses are looked up through the registry, falling back on the providers installed by terraform init.
+func (pro *Processor) FromTerraformLock(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromTerraformLock(path))
+}
+
+func (pro *Processor) fromTerraformLock(path string) (deps []Dep, err error) {
+	providers, err := terraform.ReadLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	direct, err := terraform.Dependencies(dir)
+	if err != nil {
+		return nil, err
+	}
+	// providers used without being declared are implicitly required
+	known := len(direct) > 0
+
+	for _, p := range providers {
+		fallback := func() []string {
+			return dirLicense(depsdev.TERRAFORM, p.Source, p.Version, terraform.Installed(dir, p))
+		}
+
+		deps = append(deps, Dep{
+			Context:  path,
+			Type:     depsdev.TERRAFORM,
+			Name:     p.Source,
+			Version:  p.Version,
+			Indirect: known && !slicez.Contains(direct, p.Source),
+			deferred: &deferred{dir: dir, fallback: fallback},
+		})
+	}
+	return deps, nil
+}
+
+// FromHelmLock reads the subcharts pinned in Chart.lock, all of which are dependencies of the chart itself.
+// Licenses are looked up through Artifact Hub, falling back on the charts directory.
+func (pro *Processor) FromHelmLock(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromHelmLock(path))
+}
+
+func (pro *Processor) fromHelmLock(path string) (deps []Dep, err error) {
+	lock, err := helm.ReadLockFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range lock.Dependencies {
+		// Ignore charts within the project itself
+		if d.Local() {
+			continue
+		}
+
+		fallback := func() []string {
+			return helmLocalLicense(filepath.Dir(path), d)
+		}
+
+		deps = append(deps, Dep{
+			Context:  path,
+			Type:     depsdev.HELM,
+			Name:     d.ID(),
+			Version:  d.Version,
+			deferred: &deferred{dir: filepath.Dir(path), fallback: fallback},
+		})
+	}
+	return deps, nil
+}
+
+// helmLocalLicense reads the license of a subchart fetched into the charts directory by helm dependency build
+func helmLocalLicense(chartDir string, d helm.Dependency) []string {
+	licenses, err := helm.Licenses(chartDir, d)
+	if err != nil {
+		log.WithError(err).Warnf("helm; could not find %s %s in charts, run helm dependency build to resolve its license", d.ID(), d.Version)
+		return []string{"~unknown"}
+	}
+	if len(licenses) == 0 {
+		log.Warnf("helm; could not recognise the license of %s %s in %s", d.ID(), d.Version, filepath.Join(chartDir, "charts"))
+		return []string{"~unknown"}
+	}
+	log.Infof("helm; license of %s %s from %s", d.ID(), d.Version, filepath.Join(chartDir, "charts"))
+	return licenses
+}
+
+// FromWorkflow reads the actions and reusable workflows used by a github workflow or composite action. Actions not
+// pinned to a commit sha are reported as issues, as the tag or branch they refer to can be moved to other code.
+func (pro *Processor) FromWorkflow(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromWorkflow(path))
+}
+
+func (pro *Processor) fromWorkflow(path string) (deps []Dep, err error) {
+	workflow, err := actions.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, uses := range workflow.Uses() {
+		ref, ok := actions.ParseRef(uses)
+		// Ignore actions within the repository itself and docker images
+		if !ok {
+			continue
+		}
+
+		dep := Dep{
+			Context:  path,
+			Type:     depsdev.ACTIONS,
+			Name:     ref.Name(),
+			Version:  ref.Ref,
+			deferred: &deferred{dir: filepath.Dir(path)},
+		}
+		if !ref.Pinned() {
+			dep.Issues = append(dep.Issues, fmt.Sprintf("action %s is not pinned to a commit sha in %s", ref, path))
+		}
+		deps = append(deps, dep)
+	}
+	return slicez.UniqBy(deps, func(a Dep) string {
+		return a.Key()
+	}), nil
+}
+
+// FromSBOM reads the components of a CycloneDX or SPDX document that are identified by a package url. Licenses stated
+// in the document are used as is, others are looked up as for the lockfile of the ecosystem.
+func (pro *Processor) FromSBOM(path string) ([]Dep, error) {
+	return pro.resolve(pro.fromSBOM(path))
+}
+
+func (pro *Processor) fromSBOM(path string) (deps []Dep, err error) {
+	components, err := sbom.Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range components {
+		if c.PURL == "" {
+			log.Warnf("sbom; component %s in %s has no package url, skipping", c.Name, path)
+			continue
+		}
+		purl, err := sbom.ParsePURL(c.PURL)
+		if err != nil {
+			log.WithError(err).Warnf("sbom; skipping %s %s in %s", c.Name, c.Version, path)
+			continue
+		}
+		depType, name, version, ok := purl.Dep()
+		if !ok {
+			log.Warnf("sbom; package type %s of %s is not supported, skipping", purl.Type, c.PURL)
+			continue
+		}
+
+		dep := Dep{
+			Context:  path,
+			Type:     depType,
+			Name:     name,
+			Version:  version,
+			Indirect: !c.Direct,
+			License:  c.Licenses,
+		}
+		if len(dep.License) > 0 {
+			log.Infof("%s; license of %s %s from %s", depType, name, version, path)
+		} else {
+			dep.deferred = &deferred{dir: filepath.Dir(path)}
+		}
+		deps = append(deps, dep)
+	}
+	return slicez.UniqBy(deps, func(a Dep) string {
+		return a.Key()
+	}), nil
+}
+
+// dirLicense recognises the license files of a package unpacked locally, e.g. in a package manager cache
+func dirLicense(depType depsdev.DepType, name string, version string, dir string) []string {
+	if dir == "" {
+		log.Warnf("%s; could not find %s %s locally, fetch it to resolve its license", depType, name, version)
+		return []string{"~unknown"}
+	}
+	matches := spdx.ClassifyDir(dir)
+	if len(matches) == 0 {
+		log.Warnf("%s; could not recognise the license of %s %s in %s", depType, name, version, dir)
+		return []string{"~unknown"}
+	}
+	for _, m := range matches {
+		log.Infof("%s; license of %s %s is %s from %s (confidence %.2f)", depType, name, version, m.ID, filepath.Join(dir, m.File), m.Confidence)
+	}
+	return slicez.Map(matches, func(m spdx.Match) string {
+		return m.ID
+	})
+}
+
+func unknown(licenses []string) bool {
+	return len(licenses) == 0 || slicez.Equal(licenses, []string{"~unknown"})
+}
+
+// LicensesOf looks up the licenses of a dep with the providers of its type, in order, until one knows them.
+// Dep types without providers, and deps no provider knows, are ~unknown. Providers failing are skipped, if
+// no other provider knows the licenses their failures are returned, and kept as the errors of the dep.
+// It is safe for concurrent use, a dep is looked up once however many times it is asked for.
+func (pro *Processor) LicensesOf(depType depsdev.DepType, name string, version string) ([]string, error) {
+	return pro.licensesIn("", depType, name, version)
+}
+
+// licensesIn looks up the licenses of a dep read from a file in dir, next to which the local providers look for it.
+// A dep is looked up once, next to the first file it is read from.
+func (pro *Processor) licensesIn(dir string, depType depsdev.DepType, name string, version string) ([]string, error) {
+	key := DepKey(depType, name, version)
+
+	if dep, found := pro.lookup(key); found {
+		if len(dep.Errors) > 0 {
+			return dep.License, errors.New(strings.Join(dep.Errors, "; "))
+		}
+		return dep.License, nil
+	}
+
+	if pro.cache != nil {
+		dep, found := pro.cache.Get(key)
+
+		if found {
+			log.Infof("licenses; licence cache hit for %s", dep.Key())
+			pro.record(key, dep)
+			return dep.License, nil
+		}
+	}
+
+	f, inflight := pro.join(key)
+	if inflight {
+		<-f.done
+		return f.license, f.err
+	}
+	license, err := pro.licensesOf(key, dir, depType, name, version)
+	pro.land(key, f, license, err)
+	return license, err
+}
+
+func (pro *Processor) licensesOf(key string, dir string, depType depsdev.DepType, name string, version string) ([]string, error) {
+
+	chain := pro.providers[depType]
+	if len(chain) == 0 {
+		log.Infof("licenses; no license provider for %s", key)
+		return []string{"~unknown"}, nil
+	}
+
+	license := []string{"~unknown"}
+	var provenance string
+	var failures []string
+	for _, provider := range chain {
+		log.Infof("%s; requesting %s", provider.Name(), key)
+		var licenses []string
+		var err error
+		if p, ok := provider.(DirLicenseProvider); ok {
+			licenses, err = p.LicensesIn(dir, depType, name, version)
+		} else {
+			licenses, err = provider.Licenses(depType, name, version)
+		}
+
+		if errors.Is(err, depsdev.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.WithError(err).Warnf("%s; could not look up %s", provider.Name(), key)
+			failures = append(failures, fmt.Sprintf("could not look up the license with %s: %v", provider.Name(), err))
+			continue
+		}
+		// Falling through to the next provider
+		if unknown(licenses) {
+			continue
+		}
+
+		license = slicez.Map(licenses, func(a string) string {
+			if a == "non-standard" {
+				a = "~non-standard"
+			}
+			return a
+		})
+		provenance = provider.Name()
+		log.Infof("licenses; license of %s from %s", key, provenance)
+		break
+	}
+
+	dep := Dep{
+		Type:       depType,
+		Name:       name,
+		Version:    version,
+		License:    license,
+		Provenance: provenance,
+		Warnings:   pro.check(depType, name, version, license),
+	}
+	if provenance == "" && len(failures) > 0 {
+		dep.Errors = failures
+		pro.record(key, dep)
+		return license, errors.New(strings.Join(failures, "; "))
+	}
+	pro.record(key, dep)
+	if pro.cache != nil {
+		pro.cache.Put(dep)
+	}
+
+	return license, nil
+}
+
+// check asks the cross-checking providers of a dep type about the licenses found, failing checks are only logged
+func (pro *Processor) check(depType depsdev.DepType, name string, version string, licenses []string) []string {
+	var warnings []string
+	for _, checker := range pro.checkers[depType] {
+		w, err := checker.Check(depType, name, version, licenses)
+		if err != nil && !errors.Is(err, depsdev.ErrNotFound) {
+			log.WithError(err).Warnf("%s; could not cross-check %s", checker.Name(), DepKey(depType, name, version))
+		}
+		warnings = append(warnings, w...)
+	}
+	return warnings
 }
@@ -1,8 +1,21 @@
 package deps
 
 import (
+	"fmt"
+	"github.com/modfin/depot"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/depsdev/depsdevtest"
 	"github.com/modfin/henry/slicez"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestMultiVersionedDependencyFromNPM(t *testing.T) {
@@ -36,3 +49,720 @@ func TestMultiVersionedDependencyFromNPM(t *testing.T) {
 	}
 
 }
+
+func cachedProcessor(deps ...Dep) *Processor {
+	return New(&Cache{c: slicez.KeyBy(deps, func(a Dep) string {
+		return a.Key()
+	})})
+}
+
+func TestCargoWorkspace(t *testing.T) {
+	p := cachedProcessor(
+		Dep{Type: depsdev.CARGO, Name: "rand", Version: "0.7.3", License: []string{"MIT OR Apache-2.0"}},
+		Dep{Type: depsdev.CARGO, Name: "rand", Version: "0.8.5", License: []string{"MIT OR Apache-2.0"}},
+		Dep{Type: depsdev.CARGO, Name: "libc", Version: "0.2.150", License: []string{"MIT OR Apache-2.0"}},
+		Dep{Type: depsdev.CARGO, Name: "serde", Version: "1.0.193", License: []string{"MIT OR Apache-2.0"}},
+	)
+
+	deps, err := p.FromCargo("./cargo/workspace/test_Cargo.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := slicez.Map(deps, func(d Dep) string {
+		if d.Indirect {
+			return d.Key() + " //indirect"
+		}
+		return d.Key()
+	})
+	want := []string{
+		"cargo|rand|0.7.3",
+		"cargo|rand|0.8.5",
+		"cargo|libc|0.2.150 //indirect",
+		"cargo|serde|1.0.193",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestNuGetLockDirectPerFramework(t *testing.T) {
+	mit := []string{"MIT"}
+	p := cachedProcessor(
+		Dep{Type: depsdev.NUGET, Name: "Newtonsoft.Json", Version: "13.0.3", License: mit},
+		Dep{Type: depsdev.NUGET, Name: "Serilog", Version: "3.1.1", License: []string{"Apache-2.0"}},
+		Dep{Type: depsdev.NUGET, Name: "System.Memory", Version: "4.5.5", License: mit},
+	)
+
+	deps, err := p.FromNuGetLock("./nuget/testdata/packages.lock.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		if d.Indirect {
+			return d.Key() + " //indirect"
+		}
+		return d.Key()
+	})
+	want := []string{
+		"nuget|newtonsoft.json|13.0.3",
+		"nuget|serilog|3.1.1",
+		"nuget|system.memory|4.5.5 //indirect",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestNuGetCentralPackageManagement(t *testing.T) {
+	p := cachedProcessor(
+		Dep{Type: depsdev.NUGET, Name: "newtonsoft.json", Version: "13.0.3", License: []string{"MIT"}},
+		Dep{Type: depsdev.NUGET, Name: "Serilog", Version: "3.1.1", License: []string{"Apache-2.0"}},
+		Dep{Type: depsdev.NUGET, Name: "Dapper", Version: "2.1.24", License: []string{"Apache-2.0"}},
+	)
+
+	deps, err := p.FromMSBuildProject("./nuget/testdata/src/Api/Api.csproj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		return fmt.Sprintf("%s %s %s %d", d.Name, d.Version, strings.Join(d.License, ","), len(d.Issues))
+	})
+	// a version range has no licenses to look up, it is an issue rather than an unclear license
+	want := []string{
+		"Newtonsoft.Json 13.0.3 MIT 0",
+		"Serilog 3.1.1 Apache-2.0 0",
+		"Dapper 2.1.24 Apache-2.0 0",
+		"Polly [8.0.0, 9.0.0)  1",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestComposerLock(t *testing.T) {
+	p := New(&Cache{})
+
+	deps, err := p.FromComposerLock("./composer/testdata/composer.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		s := d.Key() + " " + strings.Join(d.License, ",") + " " + d.Provenance
+		if d.Indirect {
+			return s + " //indirect"
+		}
+		return s
+	})
+	want := []string{
+		"composer|monolog/monolog|3.5.0 MIT composer.lock",
+		"composer|psr/log|3.0.0 MIT composer.lock //indirect",
+		"composer|nette/utils|v4.0.3 (BSD-3-Clause OR GPL-2.0-only OR GPL-3.0-only) composer.lock //indirect",
+		"composer|acme/legacy|v1.0.2 ~unknown  //indirect",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestMixLock(t *testing.T) {
+	p := cachedProcessor(
+		Dep{Type: depsdev.HEX, Name: "decimal", Version: "2.1.1", License: []string{"Apache-2.0"}},
+		Dep{Type: depsdev.HEX, Name: "jason", Version: "1.4.1", License: []string{"~unknown"}},
+	)
+
+	deps, err := p.FromMixLock("./mix/testdata/mix.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		s := d.Key() + " " + strings.Join(d.License, ",")
+		if d.Indirect {
+			return s + " //indirect"
+		}
+		return s
+	})
+	want := []string{
+		"hex|decimal|2.1.1 Apache-2.0 //indirect",
+		"hex|jason|1.4.1 Apache-2.0",
+		"hex|acme_billing|0.3.0 ~unknown",
+		"hex|plug|6cd3c9f2c0c6a8a2c2bb8f2d0b0b5c3f8f1d2e3a ~unknown",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestVcpkgInstalled(t *testing.T) {
+	t.Setenv("VCPKG_ROOT", "./vcpkg/testdata/root")
+
+	deps, err := New(&Cache{}).FromVcpkg("./vcpkg/testdata/project/vcpkg.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		return d.Key() + " " + strings.Join(d.License, ",")
+	})
+	want := []string{
+		"vcpkg|fmt|10.0.0 MIT",
+		"vcpkg|zlib|1.3#1 Zlib",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSBOM(t *testing.T) {
+	p := cachedProcessor(
+		Dep{Type: depsdev.NPM, Name: "left-pad", Version: "1.3.0", License: []string{"WTFPL"}},
+	)
+
+	deps, err := p.FromFile("./sbom/testdata/app.spdx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		s := d.Key() + " " + strings.Join(d.License, ",")
+		if d.Indirect {
+			return s + " //indirect"
+		}
+		return s
+	})
+	want := []string{
+		"rpm|openssl|1:3.0.11-1 Apache-2.0",
+		"npm|left-pad|1.3.0 WTFPL",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	deps, err = p.FromSBOM("./sbom/testdata/app.cdx.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = slicez.Map(deps, func(d Dep) string {
+		s := d.Key() + " " + strings.Join(d.License, ",")
+		if d.Indirect {
+			return s + " //indirect"
+		}
+		return s
+	})
+	want = []string{
+		"npm|express|4.18.2 MIT",
+		"npm|@types/node|20.10.0 MIT //indirect",
+		"maven|org.postgresql:postgresql|42.6.0 BSD-2-Clause",
+		"deb|zlib1g|1:1.2.13.dfsg-1 ~unknown //indirect",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+// registryStandIn is a license provider knowing a fixed set of packages, standing in for a remote registry
+type registryStandIn map[string][]string
+
+func (r registryStandIn) Name() string {
+	return "stand-in"
+}
+
+func (r registryStandIn) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	l, ok := r[DepKey(depType, name, version)]
+	if !ok {
+		return nil, fmt.Errorf("no %s: %w", DepKey(depType, name, version), depsdev.ErrNotFound)
+	}
+	return l, nil
+}
+
+func TestGemfileLockPlatform(t *testing.T) {
+	lock := `GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.15.4-x86_64-linux)
+
+PLATFORMS
+  x86_64-linux
+
+DEPENDENCIES
+  nokogiri (~> 1.15)
+`
+	path := filepath.Join(t.TempDir(), "Gemfile.lock")
+	if err := os.WriteFile(path, []byte(lock), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := cachedProcessor().WithProvider(depsdev.RUBYGEMS, registryStandIn{
+		"rubygems|nokogiri|1.15.4": {"MIT"},
+	})
+
+	// the dep is looked up by the version it is kept as, whatever the platform
+	deps, err := p.FromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 1 || deps[0].Key() != "rubygems|nokogiri|1.15.4" || strings.Join(deps[0].License, ",") != "MIT" || deps[0].Provenance != "stand-in" {
+		t.Fatalf("expected nokogiri 1.15.4 MIT from the stand-in, got %+v", deps)
+	}
+}
+
+func TestTerraformLock(t *testing.T) {
+	p := cachedProcessor().WithProvider(depsdev.TERRAFORM, registryStandIn{
+		"terraform|registry.terraform.io/hashicorp/aws|5.31.0": {"MPL-2.0"},
+	})
+
+	deps, err := p.FromFile("./terraform/testdata/.terraform.lock.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		s := d.Key() + " " + strings.Join(d.License, ",")
+		if d.Indirect {
+			return s + " //indirect"
+		}
+		return s
+	})
+	want := []string{
+		"terraform|registry.terraform.io/hashicorp/aws|5.31.0 MPL-2.0",
+		"terraform|registry.terraform.io/hashicorp/random|3.6.0 MIT //indirect",
+		"terraform|registry.terraform.io/integrations/github|5.42.0 ~unknown",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestHelmLock(t *testing.T) {
+	p := cachedProcessor().WithProvider(depsdev.HELM, registryStandIn{
+		"helm|registry-1.docker.io/bitnamicharts/redis|18.6.1": {"Apache-2.0"},
+	})
+
+	deps, err := p.FromFile("./helm/testdata/Chart.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		return d.Key() + " " + strings.Join(d.License, ",")
+	})
+	want := []string{
+		"helm|charts.bitnami.com/bitnami/postgresql|12.1.6 MIT",
+		"helm|registry-1.docker.io/bitnamicharts/redis|18.6.1 Apache-2.0",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestWorkflow(t *testing.T) {
+	p := cachedProcessor().WithProvider(depsdev.ACTIONS, registryStandIn{
+		"actions|actions/checkout|b4ffde65f46336ab88eb53be808477a3936bae11": {"MIT"},
+		"actions|actions/setup-go|v4":                                       {"MIT"},
+	})
+
+	deps, err := p.FromFile("./actions/testdata/.github/workflows/ci.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		return d.Key() + " " + strings.Join(d.License, ",") + " " + strings.Join(d.Issues, ";")
+	})
+	want := []string{
+		"actions|actions/checkout|b4ffde65f46336ab88eb53be808477a3936bae11 MIT ",
+		"actions|github/codeql-action|main ~unknown action github/codeql-action/init@main is not pinned to a commit sha in ./actions/testdata/.github/workflows/ci.yml",
+		"actions|octo-org/workflows|v1 ~unknown action octo-org/workflows/.github/workflows/release.yml@v1 is not pinned to a commit sha in ./actions/testdata/.github/workflows/ci.yml",
+		"actions|actions/setup-go|v4 MIT action actions/setup-go@v4 is not pinned to a commit sha in ./actions/testdata/.github/workflows/ci.yml",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestProviderChain(t *testing.T) {
+	depsDev := depsdevtest.NewServer().
+		Add(depsdev.CARGO, "serde", "1.0.193").
+		Add(depsdev.CARGO, "rand", "0.8.5", "MIT OR Apache-2.0")
+	defer depsDev.Close()
+
+	var config depot.Config
+	err := yaml.Unmarshal([]byte(`
+providers:
+  - name: deps.dev
+    url: `+depsDev.URL+`
+  - name: registry
+    type: hex
+`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := cachedProcessor().WithProviders(config.Providers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// serde is ~unknown to deps.dev and falls through to the stand-in
+	p.providers[depsdev.CARGO] = append(p.providers[depsdev.CARGO], registryStandIn{
+		"cargo|serde|1.0.193": {"MIT OR Apache-2.0"},
+	})
+
+	for _, test := range []struct {
+		name       string
+		version    string
+		license    string
+		provenance string
+	}{
+		{"rand", "0.8.5", "MIT OR Apache-2.0", "deps.dev"},
+		{"serde", "1.0.193", "MIT OR Apache-2.0", "stand-in"},
+		{"libc", "0.2.150", "~unknown", ""},
+	} {
+		l, err := p.LicensesOf(depsdev.CARGO, test.name, test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cached, _ := p.cache.Get(DepKey(depsdev.CARGO, test.name, test.version))
+		if strings.Join(l, ",") != test.license || cached.Provenance != test.provenance {
+			t.Fatalf("%s: expected %s from %q, got %v from %q", test.name, test.license, test.provenance, l, cached.Provenance)
+		}
+	}
+
+	if len(p.providers[depsdev.COMPOSER]) != 0 || len(p.providers[depsdev.RUBYGEMS]) != 0 {
+		t.Fatalf("expected no providers for composer and rubygems")
+	}
+	if len(p.providers[depsdev.HEX]) != 1 || p.providers[depsdev.HEX][0].Name() != "hex.pm" {
+		t.Fatalf("expected hex.pm as the only hex provider, got %v", p.providers[depsdev.HEX])
+	}
+	if _, err := Chains([]depot.Provider{{Name: "npmjs"}}, nil); err == nil {
+		t.Fatalf("expected an error for an unknown provider")
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	// the vendor directory is the one next to go.mod
+	p := cachedProcessor().WithProvider(depsdev.GO, NewClassifier())
+
+	deps, err := p.FromGO("./testdata/classifier/go.mod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		return d.Key() + " " + strings.Join(d.License, ",")
+	})
+	// lib is replaced by a local directory, vendored is copied into vendor and missing is nowhere
+	want := []string{
+		"go|github.com/example/lib|v0.0.0-00010101000000-000000000000 ISC",
+		"go|github.com/example/vendored|v1.2.0 BSD-2-Clause",
+		"go|github.com/example/missing|v0.3.0 ~unknown",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	cached, _ := p.cache.Get(DepKey(depsdev.GO, "github.com/example/vendored", "v1.2.0"))
+	if cached.Provenance != Classifier {
+		t.Fatalf("expected the license of vendored from %s, got %q", Classifier, cached.Provenance)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	t.Setenv("GOMODCACHE", "./testdata/local/gomodcache")
+	t.Setenv("CARGO_HOME", "./testdata/local/cargo")
+
+	p, err := cachedProcessor().WithProviders([]depot.Provider{
+		{Name: Local, Path: "./testdata/local/node_modules"},
+		{Name: "registry", Type: "hex"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		depType depsdev.DepType
+		name    string
+		version string
+		license string
+	}{
+		{depsdev.GO, "github.com/BurntSushi/toml", "v1.3.2", "MIT"},
+		{depsdev.GO, "github.com/BurntSushi/toml", "v1.4.0", "~unknown"},
+		{depsdev.NPM, "@example/widgets", "2.1.0", "ISC"},
+		// another version than the one installed
+		{depsdev.NPM, "left-pad", "1.1.3", "~unknown"},
+		{depsdev.CARGO, "serde", "1.0.193", "MIT OR Apache-2.0"},
+	} {
+		l, err := p.LicensesOf(test.depType, test.name, test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(l, ",") != test.license {
+			t.Fatalf("%s %s: expected %s, got %v", test.name, test.version, test.license, l)
+		}
+	}
+
+	chains, err := Chains(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chains[depsdev.GO][0].Name() != Local || chains[depsdev.MAVEN][0].Name() != DepsDev {
+		t.Fatalf("expected local packages to be read before asking deps.dev")
+	}
+
+	// npm packages are found in the node_modules next to the lockfile, not in the current directory
+	p = cachedProcessor().WithProvider(depsdev.NPM, NewLocal())
+	deps, err := p.FromFile("./testdata/local/package-lock.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(deps, func(d Dep) string {
+		return d.Name + " " + strings.Join(d.License, ",")
+	})
+	if want := []string{"@example/widgets ISC", "left-pad WTFPL"}; !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestLookupFailures(t *testing.T) {
+	srv := depsdevtest.NewServer().
+		Add(depsdev.NPM, "express", "4.18.2", "MIT").
+		Fail(depsdev.NPM, "express", "4.18.2", http.StatusServiceUnavailable, http.StatusTooManyRequests).
+		Add(depsdev.NPM, "left-pad", "1.3.0", "WTFPL").
+		Fail(depsdev.NPM, "left-pad", "1.3.0", 500, 500, 500, 500)
+	defer srv.Close()
+
+	client := depsdev.New().WithURL(srv.URL).WithRetries(3).WithBackoff(time.Millisecond, 10*time.Millisecond)
+	p, err := cachedProcessor().WithDepsDev(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = p.WithProviders([]depot.Provider{{Name: DepsDev}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// transient failures are retried
+	l, err := p.LicensesOf(depsdev.NPM, "express", "4.18.2")
+	if err != nil || strings.Join(l, ",") != "MIT" {
+		t.Fatalf("expected MIT, got %v, %v", l, err)
+	}
+
+	// persistent failures are the errors of the dep, which is looked up again rather than cached
+	l, err = p.LicensesOf(depsdev.NPM, "left-pad", "1.3.0")
+	if err == nil || strings.Join(l, ",") != "~unknown" {
+		t.Fatalf("expected ~unknown and an error, got %v, %v", l, err)
+	}
+	if errs := p.lookups[DepKey(depsdev.NPM, "left-pad", "1.3.0")].Errors; len(errs) != 1 || !strings.Contains(errs[0], "http status 500") {
+		t.Fatalf("expected the failure of deps.dev as the error of left-pad, got %v", errs)
+	}
+	if _, found := p.cache.Get(DepKey(depsdev.NPM, "left-pad", "1.3.0")); found {
+		t.Fatalf("expected left-pad not to be cached")
+	}
+	if got := len(srv.Requests()); got != 7 {
+		t.Fatalf("expected 3 requests for express and 4 for left-pad, got %d", got)
+	}
+}
+
+// slowStandIn takes its time to answer, counting the lookups made
+type slowStandIn struct {
+	calls *atomic.Int32
+}
+
+func (s slowStandIn) Name() string {
+	return "slow"
+}
+
+func (s slowStandIn) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	s.calls.Add(1)
+	time.Sleep(20 * time.Millisecond)
+	return []string{"MIT"}, nil
+}
+
+func TestConcurrency(t *testing.T) {
+	lock := `{"lockfileVersion": 3, "packages": {"": {"dependencies": {"pkg-0": "*"}}`
+	srv := depsdevtest.NewServer()
+	defer srv.Close()
+	for i := 0; i < 40; i++ {
+		name := fmt.Sprintf("pkg-%d", i)
+		lock += fmt.Sprintf(`, "node_modules/%s": {"version": "1.0.%d"}`, name, i)
+		if i%10 != 0 {
+			srv.Add(depsdev.NPM, name, fmt.Sprintf("1.0.%d", i), "MIT")
+		}
+	}
+	lock += "}}"
+	path := filepath.Join(t.TempDir(), "package-lock.json")
+	if err := os.WriteFile(path, []byte(lock), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(concurrency int) []string {
+		client := depsdev.New().WithURL(srv.URL).WithRateLimit(0)
+		p, err := cachedProcessor().WithDepsDev(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err = p.WithProviders([]depot.Provider{{Name: DepsDev}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		deps, err := p.WithConcurrency(concurrency).FromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return slicez.Map(deps, func(d Dep) string {
+			return d.Key() + " " + strings.Join(d.License, ",") + " " + d.Provenance
+		})
+	}
+	sequential := read(1)
+	if len(sequential) != 40 {
+		t.Fatalf("expected 40 deps, got %d", len(sequential))
+	}
+	for i := 0; i < 5; i++ {
+		if concurrent := read(8); !slicez.Equal(concurrent, sequential) {
+			t.Fatalf("expected the deps looked up concurrently to be those looked up one by one\n%v\n%v", sequential, concurrent)
+		}
+	}
+	if got := srv.Batches(); got != 6 {
+		t.Fatalf("expected the deps to be requested in a single batch per run, got %d batches", got)
+	}
+	if got := srv.Requests(); len(got) != 0 {
+		t.Fatalf("expected no dep to be requested on its own, got %v", got)
+	}
+
+	// deps asked for while being looked up are looked up once
+	var calls atomic.Int32
+	p := cachedProcessor().WithProvider(depsdev.NPM, slowStandIn{calls: &calls})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = p.LicensesOf(depsdev.NPM, "express", "4.18.2")
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single lookup of express, got %d", calls.Load())
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	var calls atomic.Int32
+	p := cachedProcessor().
+		WithProvider(depsdev.HELM, slowStandIn{calls: &calls}).
+		WithProvider(depsdev.HEX, slowStandIn{calls: &calls}).
+		WithConcurrency(2)
+
+	// deps with a local fallback, charts and hex packages, are looked up when prefetched, as any other dep
+	paths := []string{"./helm/testdata/Chart.lock", "./mix/testdata/mix.lock"}
+	p.Prefetch(paths...)
+	if calls.Load() != 4 {
+		t.Fatalf("expected the charts and hex packages to be looked up when prefetched, got %d lookups", calls.Load())
+	}
+
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.FromFile(path); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 4 {
+		t.Fatalf("expected the files prefetched not to be looked up again, got %d lookups", calls.Load())
+	}
+}
+
+func TestBatch(t *testing.T) {
+	srv := depsdevtest.NewServer()
+	defer srv.Close()
+	dir := t.TempDir()
+	var paths []string
+	for f := 0; f < 2; f++ {
+		lock := `{"lockfileVersion": 3, "packages": {"": {}`
+		for i := 0; i < 125; i++ {
+			name := fmt.Sprintf("pkg-%d-%d", f, i)
+			lock += fmt.Sprintf(`, "node_modules/%s": {"version": "1.0.0"}`, name)
+			if i%25 != 0 {
+				srv.Add(depsdev.NPM, name, "1.0.0", "MIT")
+			}
+		}
+		lock += "}}"
+		path := filepath.Join(dir, fmt.Sprintf("%d", f), "package-lock.json")
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(lock), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	p, err := cachedProcessor().WithDepsDev(depsdev.New().WithURL(srv.URL).WithRateLimit(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err = p.WithProviders([]depot.Provider{{Name: DepsDev}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the deps of both files are requested together, 250 versions answered on 3 pages
+	p.WithConcurrency(4).Prefetch(paths...)
+	if got := srv.Batches(); got != 3 {
+		t.Fatalf("expected 3 pages of batches, got %d", got)
+	}
+	unknown := 0
+	for _, path := range paths {
+		deps, err := p.FromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(deps) != 125 {
+			t.Fatalf("expected 125 deps, got %d", len(deps))
+		}
+		for _, d := range deps {
+			if strings.Join(d.License, ",") == "~unknown" {
+				unknown++
+			}
+		}
+	}
+	if unknown != 10 {
+		t.Fatalf("expected 10 deps unknown to deps.dev, got %d", unknown)
+	}
+	if got := srv.Requests(); len(got) != 0 || srv.Batches() != 3 {
+		t.Fatalf("expected the files to be answered by the batch, got %v and %d batches", got, srv.Batches())
+	}
+}
+
+func TestCrossCheck(t *testing.T) {
+	clearlyDefined := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/definitions/crate/cratesio/-/serde/1.0.193" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"licensed":{"declared":"MIT","facets":{"core":{"discovered":{"expressions":["MIT","Apache-2.0"]}}}},"scores":{"effective":80}}`))
+	}))
+	defer clearlyDefined.Close()
+
+	p, err := cachedProcessor().WithProviders([]depot.Provider{
+		{Name: ClearlyDefined, URL: clearlyDefined.URL, CrossCheck: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.WithProvider(depsdev.CARGO, registryStandIn{
+		"cargo|serde|1.0.193": {"MIT OR Apache-2.0"},
+		"cargo|libc|0.2.150":  {"MIT OR Apache-2.0"},
+	})
+
+	for key, want := range map[string]int{"serde|1.0.193": 2, "libc|0.2.150": 0} {
+		name, version, _ := strings.Cut(key, "|")
+		if _, err := p.LicensesOf(depsdev.CARGO, name, version); err != nil {
+			t.Fatal(err)
+		}
+		warnings := p.lookups[DepKey(depsdev.CARGO, name, version)].Warnings
+		if len(warnings) != want {
+			t.Fatalf("%s: expected %d warnings, got %v", name, want, warnings)
+		}
+	}
+
+	if _, err := Checkers([]depot.Provider{{Name: DepsDev, CrossCheck: true}}, nil); err == nil {
+		t.Fatalf("expected deps.dev not to cross-check")
+	}
+}
@@ -0,0 +1,199 @@
+package gem
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LockFile is a bundler Gemfile.lock
+type LockFile struct {
+	Sources []Source
+	// Dependencies are the names of the gems in the Gemfile, i.e. the direct dependencies
+	Dependencies []string
+	Platforms    []string
+}
+
+// Source is one of the GEM, GIT or PATH sections of a Gemfile.lock
+type Source struct {
+	Type     string // GEM, GIT or PATH
+	Remote   string
+	Revision string
+	Specs    []Spec
+}
+
+type Spec struct {
+	Name    string
+	Version string
+	// Platform is set for platform specific gems, e.g. nokogiri (1.15.4-x86_64-linux)
+	Platform     string
+	Dependencies []string
+}
+
+// FullVersion is the version including any platform suffix, as written in Gemfile.lock
+func (s Spec) FullVersion() string {
+	if s.Platform == "" {
+		return s.Version
+	}
+	return s.Version + "-" + s.Platform
+}
+
+const rubygemsOrg = "https://rubygems.org/"
+
+// RubyGemsOrg is true for gems from the public rubygems.org, as opposed to private gem servers
+func (s Source) RubyGemsOrg() bool {
+	return s.Type == "GEM" && strings.TrimSuffix(s.Remote, "/")+"/" == rubygemsOrg
+}
+
+var specRegexp = regexp.MustCompile(`^(\S+) \(([^)]+)\)$`)
+
+// Parse reads a Gemfile.lock, where sections are unindented headers followed by indented
+// key: value lines and a specs: list of gems at four spaces with their dependencies at six
+func Parse(r io.Reader) (LockFile, error) {
+	var lock LockFile
+	var source *Source
+	var section string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if line == "" {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		text := strings.TrimSpace(line)
+
+		if indent == 0 {
+			section = text
+			source = nil
+			switch section {
+			case "GEM", "GIT", "PATH":
+				lock.Sources = append(lock.Sources, Source{Type: section})
+				source = &lock.Sources[len(lock.Sources)-1]
+			}
+			continue
+		}
+
+		switch {
+		case source != nil && indent == 2:
+			key, value, _ := strings.Cut(text, ":")
+			switch key {
+			case "remote":
+				source.Remote = strings.TrimSpace(value)
+			case "revision":
+				source.Revision = strings.TrimSpace(value)
+			}
+
+		case source != nil && indent == 4:
+			m := specRegexp.FindStringSubmatch(text)
+			if m == nil {
+				continue
+			}
+			spec := Spec{Name: m[1], Version: m[2]}
+			// platforms follow a -, rubygems prereleases use . as in 1.0.0.rc1
+			if v, platform, found := strings.Cut(m[2], "-"); found {
+				spec.Version = v
+				spec.Platform = platform
+			}
+			source.Specs = append(source.Specs, spec)
+
+		case source != nil && indent == 6 && len(source.Specs) > 0:
+			name, _, _ := strings.Cut(text, " ")
+			last := &source.Specs[len(source.Specs)-1]
+			last.Dependencies = append(last.Dependencies, name)
+
+		case section == "DEPENDENCIES" && indent == 2:
+			name, _, _ := strings.Cut(text, " ")
+			lock.Dependencies = append(lock.Dependencies, strings.TrimSuffix(name, "!"))
+
+		case section == "PLATFORMS" && indent == 2:
+			lock.Platforms = append(lock.Platforms, text)
+		}
+	}
+	return lock, scanner.Err()
+}
+
+func ReadLockFile(path string) (LockFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return LockFile{}, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// BundlePaths are the places gems for a project may be installed, the bundle path configured in .bundle/config
+// or $BUNDLE_PATH, vendor/bundle and $GEM_HOME. Bundle paths keep gems under ruby/<version>/.
+func BundlePaths(projectDir string) []string {
+	var paths []string
+	if b, err := os.ReadFile(filepath.Join(projectDir, ".bundle", "config")); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			key, value, found := strings.Cut(line, ":")
+			if found && strings.TrimSpace(key) == "BUNDLE_PATH" {
+				paths = append(paths, resolve(projectDir, strings.Trim(strings.TrimSpace(value), `"'`)))
+			}
+		}
+	}
+	if p := os.Getenv("BUNDLE_PATH"); p != "" {
+		paths = append(paths, resolve(projectDir, p))
+	}
+	paths = append(paths, filepath.Join(projectDir, "vendor", "bundle"))
+	if p := os.Getenv("GEM_HOME"); p != "" {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func resolve(dir string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// FindGemspec locates the gemspec of an installed gem, the specification rubygems writes on install
+// or, for git sourced gems, the gemspec in the checkout bundler made
+func FindGemspec(bundlePaths []string, spec Spec, revision string) (string, bool) {
+	full := spec.Name + "-" + spec.FullVersion()
+	for _, base := range bundlePaths {
+		for _, pattern := range []string{
+			filepath.Join(base, "ruby", "*", "specifications", full+".gemspec"),
+			filepath.Join(base, "specifications", full+".gemspec"),
+		} {
+			if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+				return matches[0], true
+			}
+		}
+		if revision != "" && len(revision) >= 12 {
+			pattern := filepath.Join(base, "ruby", "*", "bundler", "gems", "*-"+revision[:12], spec.Name+".gemspec")
+			if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+				return matches[0], true
+			}
+		}
+	}
+	return "", false
+}
+
+var licenseRegexp = regexp.MustCompile(`\.licenses?\s*=\s*(\[[^\]]*\]|"[^"]*"|'[^']*')`)
+var quotedRegexp = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// Licenses reads the license or licenses attribute of a gemspec, e.g. s.licenses = ["MIT".freeze]
+func Licenses(gemspecPath string) ([]string, error) {
+	b, err := os.ReadFile(gemspecPath)
+	if err != nil {
+		return nil, err
+	}
+	var licenses []string
+	for _, m := range licenseRegexp.FindAllStringSubmatch(string(b), -1) {
+		for _, q := range quotedRegexp.FindAllStringSubmatch(m[1], -1) {
+			if l := q[1] + q[2]; l != "" {
+				licenses = append(licenses, l)
+			}
+		}
+	}
+	return licenses, nil
+}
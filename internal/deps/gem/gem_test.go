@@ -0,0 +1,106 @@
+package gem
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const lockfile = `GIT
+  remote: https://github.com/example/widget.git
+  revision: 0123456789abcdef0123456789abcdef01234567
+  specs:
+    widget (0.3.0)
+
+PATH
+  remote: .
+  specs:
+    myapp (1.0.0)
+      rack (>= 2)
+
+GEM
+  remote: https://rubygems.org/
+  specs:
+    mini_portile2 (2.8.5)
+    nokogiri (1.15.4)
+      mini_portile2 (~> 2.8.2)
+    nokogiri (1.15.4-x86_64-linux)
+    rack (3.0.8)
+
+PLATFORMS
+  ruby
+  x86_64-linux
+
+DEPENDENCIES
+  myapp!
+  nokogiri (~> 1.15)
+  widget!
+
+BUNDLED WITH
+   2.4.19
+`
+
+func TestParse(t *testing.T) {
+	lock, err := Parse(strings.NewReader(lockfile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lock.Sources) != 3 {
+		t.Fatalf("expected 3 sources, got %+v", lock.Sources)
+	}
+
+	git := lock.Sources[0]
+	if git.Type != "GIT" || git.Revision != "0123456789abcdef0123456789abcdef01234567" || git.RubyGemsOrg() {
+		t.Fatalf("unexpected git source %+v", git)
+	}
+
+	gems := lock.Sources[2]
+	if !gems.RubyGemsOrg() || len(gems.Specs) != 4 {
+		t.Fatalf("unexpected gem source %+v", gems)
+	}
+	nokogiri := gems.Specs[1]
+	if nokogiri.Name != "nokogiri" || nokogiri.Version != "1.15.4" || len(nokogiri.Dependencies) != 1 {
+		t.Fatalf("unexpected spec %+v", nokogiri)
+	}
+	native := gems.Specs[2]
+	if native.Version != "1.15.4" || native.Platform != "x86_64-linux" || native.FullVersion() != "1.15.4-x86_64-linux" {
+		t.Fatalf("unexpected platform spec %+v", native)
+	}
+
+	if strings.Join(lock.Dependencies, ",") != "myapp,nokogiri,widget" {
+		t.Fatalf("unexpected dependencies %v", lock.Dependencies)
+	}
+	if strings.Join(lock.Platforms, ",") != "ruby,x86_64-linux" {
+		t.Fatalf("unexpected platforms %v", lock.Platforms)
+	}
+}
+
+func TestGemspecLicenses(t *testing.T) {
+	dir := t.TempDir()
+	specs := filepath.Join(dir, "vendor", "bundle", "ruby", "3.2.0", "specifications")
+	if err := os.MkdirAll(specs, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gemspec := `Gem::Specification.new do |s|
+  s.name = "rack".freeze
+  s.version = "3.0.8"
+  s.licenses = ["MIT".freeze, 'Ruby'.freeze]
+end
+`
+	if err := os.WriteFile(filepath.Join(specs, "rack-3.0.8.gemspec"), []byte(gemspec), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, found := FindGemspec(BundlePaths(dir), Spec{Name: "rack", Version: "3.0.8"}, "")
+	if !found {
+		t.Fatal("expected to find the gemspec")
+	}
+	licenses, err := Licenses(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(licenses, ",") != "MIT,Ruby" {
+		t.Fatalf("unexpected licenses %v", licenses)
+	}
+}
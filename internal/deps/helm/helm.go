@@ -0,0 +1,140 @@
+package helm
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"github.com/modfin/depot/internal/spdx"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Lock is Chart.lock, written by helm dependency update, pinning the subcharts of a chart
+type Lock struct {
+	Dependencies []Dependency `yaml:"dependencies"`
+	Digest       string       `yaml:"digest"`
+}
+
+type Dependency struct {
+	Name       string `yaml:"name"`
+	Repository string `yaml:"repository"`
+	Version    string `yaml:"version"`
+}
+
+func ReadLockFile(path string) (Lock, error) {
+	var l Lock
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	err = yaml.Unmarshal(b, &l)
+	return l, err
+}
+
+// Local is true for subcharts kept within the project, referenced by a file:// repository or no repository at all
+func (d Dependency) Local() bool {
+	return d.Repository == "" || strings.HasPrefix(d.Repository, "file://")
+}
+
+// ID names a chart by its repository, without scheme, and chart name, charts.bitnami.com/bitnami/postgresql
+func (d Dependency) ID() string {
+	repo := d.Repository
+	if _, rest, found := strings.Cut(repo, "://"); found {
+		repo = rest
+	}
+	return strings.TrimSuffix(repo, "/") + "/" + d.Name
+}
+
+// Chart is the parts of Chart.yaml telling the license, which helm has no field for. Artifact Hub reads
+// the artifacthub.io/license annotation, falling back on the LICENSE file of the chart.
+type Chart struct {
+	Name        string            `yaml:"name"`
+	Version     string            `yaml:"version"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+const licenseAnnotation = "artifacthub.io/license"
+
+// Licenses reads the license of a subchart from the charts directory, either unpacked in charts/<name>
+// or as the archive helm dependency build fetches, charts/<name>-<version>.tgz
+func Licenses(chartDir string, d Dependency) ([]string, error) {
+	dir := filepath.Join(chartDir, "charts", d.Name)
+	if b, err := os.ReadFile(filepath.Join(dir, "Chart.yaml")); err == nil {
+		if l, ok := annotated(b); ok {
+			return l, nil
+		}
+		if ids, ok := spdx.FromDir(dir); ok {
+			return ids, nil
+		}
+		return nil, nil
+	}
+
+	f, err := os.Open(filepath.Join(chartDir, "charts", d.Name+"-"+d.Version+".tgz"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return archiveLicenses(f)
+}
+
+func annotated(chartYaml []byte) ([]string, bool) {
+	var c Chart
+	if yaml.Unmarshal(chartYaml, &c) != nil {
+		return nil, false
+	}
+	l := strings.TrimSpace(c.Annotations[licenseAnnotation])
+	return []string{l}, l != ""
+}
+
+// archiveLicenses reads Chart.yaml and the license files at the root of a packaged chart, <name>/Chart.yaml
+func archiveLicenses(r io.Reader) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+
+	var texts []string
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// only the files of the chart itself, not of its own subcharts
+		if strings.Count(path.Clean(h.Name), "/") != 1 || h.Typeflag != tar.TypeReg {
+			continue
+		}
+		base := path.Base(h.Name)
+		switch {
+		case base == "Chart.yaml":
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			if l, ok := annotated(b); ok {
+				return l, nil
+			}
+		case strings.HasPrefix(strings.ToUpper(base), "LICENSE") || strings.HasPrefix(strings.ToUpper(base), "LICENCE"):
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			texts = append(texts, string(b))
+		}
+	}
+
+	var ids []string
+	for _, text := range texts {
+		if id, ok := spdx.FromText(text); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,52 @@
+package helm
+
+import (
+	"github.com/modfin/henry/slicez"
+	"testing"
+)
+
+func TestReadLockFile(t *testing.T) {
+	lock, err := ReadLockFile("testdata/Chart.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(lock.Dependencies, func(d Dependency) string {
+		if d.Local() {
+			return d.Name + " " + d.Version + " local"
+		}
+		return d.ID() + " " + d.Version
+	})
+	want := []string{
+		"charts.bitnami.com/bitnami/postgresql 12.1.6",
+		"registry-1.docker.io/bitnamicharts/redis 18.6.1",
+		"common 0.1.0 local",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestLicenses(t *testing.T) {
+	tests := []struct {
+		dep  Dependency
+		want []string
+	}{
+		// unpacked, from the artifacthub.io/license annotation
+		{Dependency{Name: "common", Version: "0.1.0"}, []string{"Apache-2.0"}},
+		// packaged, from the LICENSE of the chart and not of its subcharts
+		{Dependency{Name: "postgresql", Version: "12.1.6"}, []string{"MIT"}},
+	}
+	for _, test := range tests {
+		got, err := Licenses("testdata", test.dep)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slicez.Equal(got, test.want) {
+			t.Fatalf("%s: expected %v, got %v", test.dep.Name, test.want, got)
+		}
+	}
+
+	if _, err := Licenses("testdata", Dependency{Name: "redis", Version: "18.6.1"}); err == nil {
+		t.Fatalf("expected an error for a chart not fetched")
+	}
+}
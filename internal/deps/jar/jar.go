@@ -0,0 +1,251 @@
+package jar
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/modfin/depot/internal/deps/pom"
+	"github.com/modfin/depot/internal/spdx"
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	"io"
+	"path"
+	"strings"
+)
+
+// Artifact is a maven artifact found embedded in an archive through its META-INF/maven metadata
+type Artifact struct {
+	GroupID    string
+	ArtifactID string
+	Version    string
+
+	// Archive is the chain of archive entries leading to the artifact, e.g. app.war!/WEB-INF/lib/foo.jar
+	Archive string
+	// Nested is true if the artifact was found inside an archive contained in the scanned archive
+	Nested bool
+
+	// Licenses are the license names stated in the embedded pom.xml
+	Licenses []string
+	// LicenseFiles are the bundled META-INF/LICENSE* files, by entry name, of the archive the artifact was found in
+	LicenseFiles map[string]string
+}
+
+func (a Artifact) Name() string {
+	return fmt.Sprintf("%s:%s", a.GroupID, a.ArtifactID)
+}
+
+// IsArchive returns true for the java archive formats we scan, jar, war and ear
+func IsArchive(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jar", ".war", ".ear":
+		return true
+	}
+	return false
+}
+
+const (
+	// maxDepth is how deep archives are nested at most, a jar in a war in an ear is 2 deep
+	maxDepth = 4
+	// maxRead is the bytes read at most, uncompressed, from an archive and the archives nested in it
+	maxRead = 1 << 30
+)
+
+// Scan reads all maven artifacts embedded in a java archive, recursing into nested archives
+// such as WEB-INF/lib in wars, modules in ears and BOOT-INF/lib in spring boot jars
+func Scan(r io.ReaderAt, size int64, name string) ([]Artifact, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("could not open archive %s: %w", name, err)
+	}
+	budget := int64(maxRead)
+	return scan(zr, name, 0, &budget)
+}
+
+// scan reads the artifacts of an archive depth archives deep, reading at most budget bytes, less those read
+func scan(zr *zip.Reader, name string, depth int, budget *int64) ([]Artifact, error) {
+	var artifacts []Artifact
+
+	type entry struct {
+		properties map[string]string
+		pom        *pom.PomXML
+	}
+	entries := map[string]*entry{}
+	licenseFiles := map[string]string{}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(f.Name, "META-INF/maven/") && path.Base(f.Name) == "pom.properties":
+			b, err := readAll(f, budget)
+			if err != nil {
+				return nil, err
+			}
+			dir := path.Dir(f.Name)
+			if entries[dir] == nil {
+				entries[dir] = &entry{}
+			}
+			entries[dir].properties = properties(b)
+
+		case strings.HasPrefix(f.Name, "META-INF/maven/") && path.Base(f.Name) == "pom.xml":
+			b, err := readAll(f, budget)
+			if err != nil {
+				return nil, err
+			}
+			var x pom.PomXML
+			if err := xml.Unmarshal(b, &x); err != nil {
+				// A broken pom does not stop us from using pom.properties
+				continue
+			}
+			dir := path.Dir(f.Name)
+			if entries[dir] == nil {
+				entries[dir] = &entry{}
+			}
+			entries[dir].pom = &x
+
+		case path.Dir(f.Name) == "META-INF" && strings.HasPrefix(strings.ToUpper(path.Base(f.Name)), "LICENSE"):
+			b, err := readAll(f, budget)
+			if err != nil {
+				return nil, err
+			}
+			licenseFiles[f.Name] = string(b)
+
+		case IsArchive(f.Name):
+			if depth+1 > maxDepth {
+				return nil, fmt.Errorf("archive %s!/%s is nested more than %d archives deep", name, f.Name, maxDepth)
+			}
+			b, err := readAll(f, budget)
+			if err != nil {
+				return nil, err
+			}
+			inner, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+			if err != nil {
+				// Not every file named .jar is a zip, nothing to find in it
+				continue
+			}
+			found, err := scan(inner, name+"!/"+f.Name, depth+1, budget)
+			if err != nil {
+				return nil, err
+			}
+			artifacts = append(artifacts, found...)
+		}
+	}
+
+	dirs := slicez.Sort(mapz.Keys(entries))
+	var own []Artifact
+	for _, dir := range dirs {
+		e := entries[dir]
+
+		var a Artifact
+		if e.properties != nil {
+			a.GroupID = e.properties["groupId"]
+			a.ArtifactID = e.properties["artifactId"]
+			a.Version = e.properties["version"]
+		}
+		if e.pom != nil {
+			p := pom.POM{Content: e.pom}
+			a.Licenses = p.Licenses()
+
+			// pom.properties is authoritative, but is not always present
+			if a.GroupID == "" {
+				a.GroupID = coalesce(e.pom.GroupId, e.pom.Parent.GroupId)
+			}
+			if a.ArtifactID == "" {
+				a.ArtifactID = e.pom.ArtifactId
+			}
+			if a.Version == "" {
+				a.Version = coalesce(e.pom.Version, e.pom.Parent.Version)
+			}
+		}
+		if a.GroupID == "" || a.ArtifactID == "" || a.Version == "" || strings.Contains(a.Version, "${") {
+			continue
+		}
+		a.Archive = name
+		a.Nested = depth > 0
+		own = append(own, a)
+	}
+
+	// Bundled license files can only be attributed when the archive holds a single artifact,
+	// shaded jars contain many and the license file belongs to any one of them
+	if len(own) == 1 && len(licenseFiles) > 0 {
+		own[0].LicenseFiles = licenseFiles
+	}
+
+	return append(own, artifacts...), nil
+}
+
+// readAll reads an entry of an archive, no more than its stated size, taking what is read from the budget
+func readAll(f *zip.File, budget *int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(*budget) {
+		return nil, fmt.Errorf("could not read %s: over the %d bytes read at most, uncompressed, of an archive", f.Name, maxRead)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(io.LimitReader(rc, int64(f.UncompressedSize64)+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", f.Name, err)
+	}
+	if uint64(len(b)) > f.UncompressedSize64 {
+		return nil, fmt.Errorf("could not read %s: larger than the %d bytes stated", f.Name, f.UncompressedSize64)
+	}
+	*budget -= int64(len(b))
+	return b, nil
+}
+
+// properties parses the java .properties format as written by maven, key=value lines and # comments
+func properties(b []byte) map[string]string {
+	props := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		k, v, found := strings.Cut(line, "=")
+		if !found {
+			k, v, found = strings.Cut(line, ":")
+		}
+		if !found {
+			continue
+		}
+		props[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return props
+}
+
+func coalesce(s ...string) string {
+	for _, v := range s {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// SPDX returns the SPDX identifiers the artifact declares itself, through its embedded pom
+// or, failing that, a bundled license file
+func (a Artifact) SPDX() []string {
+	var ids []string
+	for _, name := range a.Licenses {
+		if id, ok := spdx.FromName(name); ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		return slicez.Uniq(ids)
+	}
+
+	for _, name := range slicez.Sort(mapz.Keys(a.LicenseFiles)) {
+		if id, ok := spdx.FromText(a.LicenseFiles[name]); ok {
+			ids = append(ids, id)
+		}
+	}
+	return slicez.Uniq(ids)
+}
@@ -0,0 +1,103 @@
+package jar
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func zipOf(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = f.Write(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestScanSpringBootJar(t *testing.T) {
+	lib := zipOf(t, map[string][]byte{
+		"META-INF/maven/org.slf4j/slf4j-api/pom.properties": []byte("#Generated by Maven\ngroupId=org.slf4j\nartifactId=slf4j-api\nversion=2.0.9\n"),
+		"META-INF/maven/org.slf4j/slf4j-api/pom.xml":        []byte("<project><licenses><license><name>MIT License</name></license></licenses></project>"),
+	})
+	app := zipOf(t, map[string][]byte{
+		"META-INF/maven/com.example/app/pom.properties": []byte("groupId=com.example\nartifactId=app\nversion=1.0.0\n"),
+		"META-INF/LICENSE.txt":                          []byte("Apache License\n Version 2.0, January 2004"),
+		"BOOT-INF/lib/slf4j-api-2.0.9.jar":              lib,
+	})
+
+	artifacts, err := Scan(bytes.NewReader(app), int64(len(app)), "app.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %d", len(artifacts))
+	}
+
+	own, nested := artifacts[0], artifacts[1]
+	if own.Name() != "com.example:app" || own.Version != "1.0.0" || own.Nested {
+		t.Fatalf("unexpected artifact %+v", own)
+	}
+	if l := own.SPDX(); len(l) != 1 || l[0] != "Apache-2.0" {
+		t.Fatalf("expected license from bundled LICENSE file, got %v", l)
+	}
+
+	if nested.Name() != "org.slf4j:slf4j-api" || nested.Version != "2.0.9" || !nested.Nested {
+		t.Fatalf("unexpected artifact %+v", nested)
+	}
+	if nested.Archive != "app.jar!/BOOT-INF/lib/slf4j-api-2.0.9.jar" {
+		t.Fatalf("unexpected archive path %s", nested.Archive)
+	}
+	if l := nested.SPDX(); len(l) != 1 || l[0] != "MIT" {
+		t.Fatalf("expected license from embedded pom, got %v", l)
+	}
+}
+
+func TestScanLimits(t *testing.T) {
+	lib := zipOf(t, map[string][]byte{
+		"META-INF/maven/org.slf4j/slf4j-api/pom.properties": []byte("groupId=org.slf4j\nartifactId=slf4j-api\nversion=2.0.9\n"),
+	})
+
+	// archives nested deeper than maxDepth fail, rather than being read without end
+	nested := lib
+	for i := 0; i < maxDepth; i++ {
+		nested = zipOf(t, map[string][]byte{"lib/nested.jar": nested})
+	}
+	if _, err := Scan(bytes.NewReader(nested), int64(len(nested)), "app.jar"); err != nil {
+		t.Fatalf("expected archives %d deep to be read, got %v", maxDepth, err)
+	}
+	nested = zipOf(t, map[string][]byte{"lib/nested.jar": nested})
+	if _, err := Scan(bytes.NewReader(nested), int64(len(nested)), "app.jar"); err == nil {
+		t.Fatalf("expected archives more than %d deep to fail", maxDepth)
+	}
+
+	// the archives nested are read, uncompressed, within a budget shared by all of them
+	app := zipOf(t, map[string][]byte{
+		"lib/a.jar":            lib,
+		"lib/b.jar":            lib,
+		"META-INF/LICENSE.txt": bytes.Repeat([]byte("a"), 1024),
+	})
+	zr, err := zip.NewReader(bytes.NewReader(app), int64(len(app)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := int64(2*len(lib) + 1024 + 200)
+	if _, err := scan(zr, "app.jar", 0, &budget); err != nil {
+		t.Fatalf("expected the archive to be read within its budget, got %v", err)
+	}
+	budget = int64(len(lib) + 1024)
+	if _, err := scan(zr, "app.jar", 0, &budget); err == nil {
+		t.Fatal("expected the archive to fail once over its budget")
+	}
+}
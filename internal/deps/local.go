@@ -0,0 +1,133 @@
+package deps
+
+import (
+	"encoding/json"
+	"github.com/modfin/depot/internal/deps/cargo"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/registry"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/mod/module"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalProvider is an offline license provider, reading the licenses of packages the package managers of the
+// developer have already fetched. Go modules are found extracted in the module cache, npm packages in
+// node_modules and crates among the sources in the cargo home. Packages not fetched are ~unknown.
+type LocalProvider struct {
+	goModCache string
+	cargoHome  string
+	// nodeModules are where npm packages are looked for, if not in the node_modules next to the lockfile
+	nodeModules []string
+}
+
+// NewLocal looks for npm packages in the given node_modules directories, or the node_modules next to the lockfile
+func NewLocal(nodeModules ...string) LocalProvider {
+	return LocalProvider{
+		goModCache:  goModCache(),
+		cargoHome:   cargo.Home(),
+		nodeModules: nodeModules,
+	}
+}
+
+// goModCache is where go extracts modules, $GOMODCACHE or the pkg/mod of the first $GOPATH, by default ~/go
+func goModCache() string {
+	if cache := os.Getenv("GOMODCACHE"); cache != "" {
+		return cache
+	}
+	if gopath := filepath.SplitList(os.Getenv("GOPATH")); len(gopath) > 0 && gopath[0] != "" {
+		return filepath.Join(gopath[0], "pkg", "mod")
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go", "pkg", "mod")
+}
+
+func (l LocalProvider) Name() string {
+	return Local
+}
+
+func (l LocalProvider) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	return l.LicensesIn("", depType, name, version)
+}
+
+// LicensesIn looks up a package read from a lockfile in dir
+func (l LocalProvider) LicensesIn(dir string, depType depsdev.DepType, name string, version string) ([]string, error) {
+	switch depType {
+	case depsdev.GO:
+		return l.goLicenses(name, version), nil
+	case depsdev.NPM:
+		return l.npmLicenses(dir, name, version), nil
+	case depsdev.CARGO:
+		return l.cargoLicenses(name, version), nil
+	}
+	return []string{"~unknown"}, nil
+}
+
+// goLicenses recognises the license files of a module extracted in the module cache, at <path>@<version>
+// with upper case letters escaped as !<lower case>
+func (l LocalProvider) goLicenses(name string, version string) []string {
+	path, err := module.EscapePath(name)
+	if err != nil {
+		return []string{"~unknown"}
+	}
+	v, err := module.EscapeVersion(version)
+	if err != nil {
+		return []string{"~unknown"}
+	}
+	dir := filepath.Join(l.goModCache, path+"@"+v)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return []string{"~unknown"}
+	}
+	return dirLicense(depsdev.GO, name, version, dir)
+}
+
+// npmLicenses reads the license of the package.json of a package installed in node_modules, if it is
+// the version asked for
+func (l LocalProvider) npmLicenses(dir string, name string, version string) []string {
+	roots := l.nodeModules
+	if len(roots) == 0 {
+		roots = []string{filepath.Join(dir, "node_modules")}
+	}
+	for _, nodeModules := range roots {
+		path := filepath.Join(nodeModules, filepath.FromSlash(name), "package.json")
+		b, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var pkg registry.NPMVersion
+		if err := json.Unmarshal(b, &pkg); err != nil {
+			log.WithError(err).Warnf("npm; could not read %s", path)
+			continue
+		}
+		if pkg.Version != version {
+			continue
+		}
+		if licenses := pkg.SPDX(); len(licenses) > 0 {
+			log.Infof("npm; license of %s %s from %s", name, version, path)
+			return licenses
+		}
+	}
+	return []string{"~unknown"}
+}
+
+// cargoLicenses reads the license of the Cargo.toml of a crate cargo has unpacked from crates.io
+func (l LocalProvider) cargoLicenses(name string, version string) []string {
+	// the sparse index is the default since cargo 1.70, the git index before it
+	for _, source := range []string{"sparse+https://index.crates.io/", "registry+https://github.com/rust-lang/crates.io-index"} {
+		manifest, root, err := cargo.FindManifest(l.cargoHome, cargo.Pkg{Name: name, Version: version, Source: source})
+		if err != nil {
+			continue
+		}
+		license, err := cargo.LicenseOf(manifest, root)
+		if err != nil || strings.TrimSpace(license) == "" {
+			continue
+		}
+		log.Infof("cargo; license of %s %s from %s", name, version, manifest)
+		return []string{license}
+	}
+	return []string{"~unknown"}
+}
@@ -0,0 +1,140 @@
+package mix
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Lock is an entry of mix.lock, an elixir map of app name to a tuple, one per line:
+//
+//	"jason": {:hex, :jason, "1.4.1", "<checksum>", [:mix], [<deps>], "hexpm", "<checksum>"},
+//	"plug": {:git, "https://github.com/elixir-plug/plug.git", "<revision>", [branch: "main"]},
+type Lock struct {
+	App string
+	// Source is hex, git or path
+	Source string
+	// Package is the name of the package on hex, which may differ from the app name
+	Package string
+	Version string
+	// Repo is the hex repository, hexpm for the public hex.pm or hexpm:<organisation>
+	Repo string
+	URL  string
+}
+
+func (l Lock) HexPM() bool {
+	return l.Source == "hex" && l.Repo == "hexpm"
+}
+
+var lockRegexp = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*\{:(\w+),\s*(.*)\},?\s*$`)
+var atomRegexp = regexp.MustCompile(`^:"?([\w.]+)"?`)
+var quotedRegexp = regexp.MustCompile(`"([^"]*)"`)
+
+func ReadLockFile(path string) ([]Lock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var locks []Lock
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		m := lockRegexp.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		l := Lock{App: m[1], Source: m[2]}
+		rest := strings.TrimSpace(m[3])
+		quoted := quotedRegexp.FindAllStringSubmatch(rest, -1)
+
+		switch l.Source {
+		case "hex":
+			if a := atomRegexp.FindStringSubmatch(rest); a != nil {
+				l.Package = a[1]
+			}
+			if len(quoted) > 0 {
+				l.Version = quoted[0][1]
+			}
+			// Lockfiles written before mix 1.7 end with the deps list and have no repo
+			l.Repo = "hexpm"
+			if !strings.HasSuffix(rest, "]") && len(quoted) >= 2 {
+				l.Repo = quoted[len(quoted)-2][1]
+			}
+		case "git":
+			if len(quoted) >= 2 {
+				l.URL = quoted[0][1]
+				l.Version = quoted[1][1]
+			}
+		}
+		locks = append(locks, l)
+	}
+	return locks, scanner.Err()
+}
+
+var depRegexp = regexp.MustCompile(`\{\s*:(\w+)\s*,`)
+var onlyRegexp = regexp.MustCompile(`only:\s*(\[[^\]]*\]|:\w+)`)
+
+// Dependencies reads the deps a mix.exs declares, and those of the apps in an umbrella project.
+// The manifest is elixir code, so only the conventional deps function listing {:app, ...} tuples one per line is
+// recognised. Deps only for the dev and test environments are returned separately.
+func Dependencies(projectDir string) (deps []string, dev []string, err error) {
+	manifests := []string{filepath.Join(projectDir, "mix.exs")}
+	apps, _ := filepath.Glob(filepath.Join(projectDir, "apps", "*", "mix.exs"))
+	manifests = append(manifests, apps...)
+
+	for i, manifest := range manifests {
+		b, err := os.ReadFile(manifest)
+		if err != nil {
+			if i == 0 {
+				return nil, nil, err
+			}
+			continue
+		}
+		text := string(b)
+		start := strings.Index(text, "defp deps")
+		if start < 0 {
+			continue
+		}
+		text = text[start:]
+		if end := strings.Index(text[1:], "\n  defp "); end >= 0 {
+			text = text[:end+1]
+		}
+
+		for _, line := range strings.Split(text, "\n") {
+			m := depRegexp.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			if only := onlyRegexp.FindStringSubmatch(line); only != nil && !strings.Contains(only[1], ":prod") {
+				dev = append(dev, m[1])
+				continue
+			}
+			deps = append(deps, m[1])
+		}
+	}
+	return deps, dev, nil
+}
+
+var hexLicensesRegexp = regexp.MustCompile(`\{<<"licenses">>\s*,\s*\[([^\]]*)\]\}`)
+var binaryRegexp = regexp.MustCompile(`<<"([^"]*)">>`)
+
+// Licenses reads the licenses from the hex_metadata.config mix writes into deps/<app> when fetching a hex package
+func Licenses(depDir string) ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(depDir, "hex_metadata.config"))
+	if err != nil {
+		return nil, err
+	}
+	m := hexLicensesRegexp.FindStringSubmatch(string(b))
+	if m == nil {
+		return nil, nil
+	}
+	var licenses []string
+	for _, l := range binaryRegexp.FindAllStringSubmatch(m[1], -1) {
+		licenses = append(licenses, l[1])
+	}
+	return licenses, nil
+}
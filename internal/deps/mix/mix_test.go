@@ -0,0 +1,49 @@
+package mix
+
+import (
+	"github.com/modfin/henry/slicez"
+	"testing"
+)
+
+func TestReadLockFile(t *testing.T) {
+	locks, err := ReadLockFile("./testdata/mix.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(locks, func(l Lock) string {
+		return l.App + " " + l.Source + " " + l.Package + " " + l.Version + " " + l.Repo
+	})
+	want := []string{
+		"decimal hex decimal 2.1.1 hexpm",
+		"jason hex jason 1.4.1 hexpm",
+		"credo hex credo 1.7.1 hexpm",
+		"billing hex acme_billing 0.3.0 hexpm:acme",
+		"plug git  6cd3c9f2c0c6a8a2c2bb8f2d0b0b5c3f8f1d2e3a ",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	deps, dev, err := Dependencies("./testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicez.Equal(deps, []string{"jason", "billing", "plug"}) {
+		t.Fatalf("unexpected deps %v", deps)
+	}
+	if !slicez.Equal(dev, []string{"credo"}) {
+		t.Fatalf("unexpected dev deps %v", dev)
+	}
+}
+
+func TestLicenses(t *testing.T) {
+	licenses, err := Licenses("./testdata/deps/jason")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicez.Equal(licenses, []string{"Apache-2.0"}) {
+		t.Fatalf("unexpected licenses %v", licenses)
+	}
+}
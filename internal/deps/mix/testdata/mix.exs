@@ -0,0 +1,16 @@
+defmodule Shop.MixProject do
+  use Mix.Project
+
+  def project do
+    [app: :shop, version: "0.1.0", deps: deps()]
+  end
+
+  defp deps do
+    [
+      {:jason, "~> 1.4"},
+      {:billing, "~> 0.3", organization: "acme"},
+      {:plug, github: "elixir-plug/plug", branch: "main"},
+      {:credo, "~> 1.7", only: [:dev, :test], runtime: false}
+    ]
+  end
+end
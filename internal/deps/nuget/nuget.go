@@ -0,0 +1,198 @@
+package nuget
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LockFile is packages.lock.json, with dependencies by target framework,
+// e.g. net8.0 or net8.0/linux-x64 for runtime specific graphs
+type LockFile struct {
+	Version      int                              `json:"version"`
+	Dependencies map[string]map[string]Dependency `json:"dependencies"`
+}
+
+type Dependency struct {
+	// Type is Direct, Transitive, CentralTransitive or Project
+	Type         string            `json:"type"`
+	Requested    string            `json:"requested"`
+	Resolved     string            `json:"resolved"`
+	ContentHash  string            `json:"contentHash"`
+	Dependencies map[string]string `json:"dependencies"`
+}
+
+func (d Dependency) Direct() bool {
+	return d.Type == "Direct"
+}
+
+// Project is an msbuild project file, *.csproj, *.fsproj, *.vbproj or Directory.Packages.props
+type Project struct {
+	ItemGroups []struct {
+		PackageReference []PackageReference `xml:"PackageReference"`
+		PackageVersion   []PackageReference `xml:"PackageVersion"`
+	} `xml:"ItemGroup"`
+	PropertyGroups []struct {
+		ManagePackageVersionsCentrally string `xml:"ManagePackageVersionsCentrally"`
+	} `xml:"PropertyGroup"`
+}
+
+// PackageReference is a PackageReference or PackageVersion item.
+// Msbuild allows the metadata both as attributes and as child elements.
+type PackageReference struct {
+	Include         string `xml:"Include,attr"`
+	Update          string `xml:"Update,attr"`
+	Version         string `xml:"Version,attr"`
+	VersionOverride string `xml:"VersionOverride,attr"`
+	PrivateAssets   string `xml:"PrivateAssets,attr"`
+
+	VersionElement         string `xml:"Version"`
+	VersionOverrideElement string `xml:"VersionOverride"`
+	PrivateAssetsElement   string `xml:"PrivateAssets"`
+}
+
+func (r PackageReference) Name() string {
+	if r.Include != "" {
+		return r.Include
+	}
+	return r.Update
+}
+
+func (r PackageReference) GetVersion() string {
+	for _, v := range []string{r.VersionOverride, r.VersionOverrideElement, r.Version, r.VersionElement} {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// DevelopmentOnly is true for references with PrivateAssets all, e.g. analyzers and build tools
+// that do not flow to consumers of the project
+func (r PackageReference) DevelopmentOnly() bool {
+	return strings.EqualFold(strings.TrimSpace(r.PrivateAssets+r.PrivateAssetsElement), "all")
+}
+
+func (p Project) References() []PackageReference {
+	var refs []PackageReference
+	for _, g := range p.ItemGroups {
+		refs = append(refs, g.PackageReference...)
+	}
+	return refs
+}
+
+func (p Project) Versions() []PackageReference {
+	var refs []PackageReference
+	for _, g := range p.ItemGroups {
+		refs = append(refs, g.PackageVersion...)
+	}
+	return refs
+}
+
+func (p Project) CentrallyManaged() (bool, bool) {
+	for _, g := range p.PropertyGroups {
+		if v := strings.TrimSpace(g.ManagePackageVersionsCentrally); v != "" {
+			return strings.EqualFold(v, "true"), true
+		}
+	}
+	return false, false
+}
+
+func ReadProject(path string) (Project, error) {
+	var p Project
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	err = xml.Unmarshal(b, &p)
+	return p, err
+}
+
+// FindPackagesProps finds the Directory.Packages.props that applies to a project, the closest one in the project
+// directory or above, as msbuild does
+func FindPackagesProps(projectPath string) (string, bool) {
+	dir, err := filepath.Abs(filepath.Dir(projectPath))
+	if err != nil {
+		return "", false
+	}
+	for {
+		props := filepath.Join(dir, "Directory.Packages.props")
+		if _, err := os.Stat(props); err == nil {
+			return props, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// PackagesConfig is the legacy packages.config
+type PackagesConfig struct {
+	Packages []struct {
+		ID                    string `xml:"id,attr"`
+		Version               string `xml:"version,attr"`
+		TargetFramework       string `xml:"targetFramework,attr"`
+		DevelopmentDependency bool   `xml:"developmentDependency,attr"`
+	} `xml:"package"`
+}
+
+// IsProject tells if a file name is an msbuild project file referencing packages
+func IsProject(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csproj", ".fsproj", ".vbproj":
+		return true
+	}
+	return false
+}
+
+// ExactVersion reads a nuget version or version range. A plain version, 1.2.3, is a minimum version
+// which nuget resolves to exactly that version when it exists, as does [1.2.3]. Other ranges are not exact.
+func ExactVersion(v string) (string, bool) {
+	v = strings.TrimSpace(v)
+	switch {
+	case v == "":
+		return "", false
+	case strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") && !strings.Contains(v, ","):
+		return strings.TrimSpace(v[1 : len(v)-1]), true
+	case strings.ContainsAny(v, "[](),*$"):
+		return "", false
+	}
+	return v, true
+}
+
+// NormalizeVersion returns the form nuget compares versions in, 1.0 and 1.0.0.0 are both 1.0.0
+// ref. https://learn.microsoft.com/en-us/nuget/concepts/package-versioning#normalized-version-numbers
+func NormalizeVersion(v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	release, suffix := v, ""
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		release, suffix = v[:i], v[i:]
+	}
+	// build metadata is not part of the version identity
+	if i := strings.Index(suffix, "+"); i >= 0 {
+		suffix = suffix[:i]
+	}
+
+	if strings.Trim(release, "0123456789.") != "" || release == "" {
+		return v
+	}
+
+	parts := strings.Split(release, ".")
+	for i, p := range parts {
+		p = strings.TrimLeft(p, "0")
+		if p == "" {
+			p = "0"
+		}
+		parts[i] = p
+	}
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	if len(parts) == 4 && parts[3] == "0" {
+		parts = parts[:3]
+	}
+	return strings.Join(parts, ".") + suffix
+}
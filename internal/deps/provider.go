@@ -0,0 +1,243 @@
+package deps
+
+import (
+	"fmt"
+	"github.com/modfin/depot"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"github.com/modfin/depot/internal/registry"
+	"github.com/modfin/henry/slicez"
+)
+
+// LicenseProvider looks up the licenses of a package version. Providers report versions they
+// do not know with an error wrapping depsdev.ErrNotFound, as depsdev.Client does.
+type LicenseProvider interface {
+	Name() string
+	Licenses(depType depsdev.DepType, name string, version string) ([]string, error)
+}
+
+// DirLicenseProvider is a provider looking for packages next to the file they are read from, e.g. in the
+// node_modules or vendor directory of the project. Licenses looks for them in the current directory.
+type DirLicenseProvider interface {
+	LicenseProvider
+	LicensesIn(dir string, depType depsdev.DepType, name string, version string) ([]string, error)
+}
+
+// LicenseChecker cross-checks the licenses found for a package version, reporting disagreements as warnings
+type LicenseChecker interface {
+	Name() string
+	Check(depType depsdev.DepType, name string, version string, licenses []string) ([]string, error)
+}
+
+// Names of the providers in .depot.yml
+const (
+	DepsDev        = "deps.dev"
+	Registry       = "registry"
+	ClearlyDefined = "clearlydefined"
+	Classifier     = "classifier"
+	Local          = "local"
+)
+
+// defaultProviders first reads the packages already fetched locally, then asks deps.dev and the registry of the
+// ecosystem, for versions deps.dev does not know yet and ecosystems it does not cover, and last recognises the
+// license texts of vendored packages
+var defaultProviders = []depot.Provider{{Name: Local}, {Name: DepsDev}, {Name: Registry}, {Name: Classifier}}
+
+// Chains builds the ordered license providers of every dep type from the providers configured in .depot.yml,
+// not counting those cross-checking, asking deps.dev with the given client.
+// Dep types no provider serves have no chain, their licenses are only known from local metadata.
+func Chains(config []depot.Provider, depsDev *depsdev.Client) (map[depsdev.DepType][]LicenseProvider, error) {
+	config = slicez.Filter(config, func(c depot.Provider) bool {
+		return !c.CrossCheck
+	})
+	if len(config) == 0 {
+		config = defaultProviders
+	}
+	if depsDev == nil {
+		depsDev = depsdev.New()
+	}
+
+	chains := map[depsdev.DepType][]LicenseProvider{}
+	for _, c := range config {
+		for _, t := range depsdev.Types {
+			p, err := providerOf(c, t, depsDev)
+			if err != nil {
+				return nil, err
+			}
+			if p != nil {
+				chains[t] = append(chains[t], p)
+			}
+		}
+	}
+	return chains, nil
+}
+
+// Checkers are the providers configured in .depot.yml to cross-check licenses with, by dep type
+func Checkers(config []depot.Provider, depsDev *depsdev.Client) (map[depsdev.DepType][]LicenseChecker, error) {
+	if depsDev == nil {
+		depsDev = depsdev.New()
+	}
+	checkers := map[depsdev.DepType][]LicenseChecker{}
+	for _, c := range config {
+		if !c.CrossCheck {
+			continue
+		}
+		for _, t := range depsdev.Types {
+			p, err := providerOf(c, t, depsDev)
+			if err != nil {
+				return nil, err
+			}
+			if p == nil {
+				continue
+			}
+			checker, ok := p.(LicenseChecker)
+			if !ok {
+				return nil, fmt.Errorf("license provider %s can not cross-check licenses", c.Name)
+			}
+			checkers[t] = append(checkers[t], checker)
+		}
+	}
+	return checkers, nil
+}
+
+// providerOf is the provider configured for a dep type, or nil if it does not serve the type
+func providerOf(c depot.Provider, t depsdev.DepType, depsDev *depsdev.Client) (LicenseProvider, error) {
+	if c.Type != "" && c.Type != string(t) {
+		return nil, nil
+	}
+	switch c.Name {
+	case DepsDev:
+		if !depsdev.Serves(t) {
+			return nil, nil
+		}
+		if c.URL != "" {
+			return depsDev.Clone().WithURL(c.URL), nil
+		}
+		return depsDev, nil
+	case Registry:
+		return registryOf(t, c.URL, depsDev.Fetch()), nil
+	case ClearlyDefined:
+		if registry.ServesClearlyDefined(t) {
+			client := registry.NewClearlyDefined().WithFetch(depsDev.Fetch())
+			return withURL(client, client.WithURL, c.URL), nil
+		}
+		return nil, nil
+	case Classifier:
+		if c.Path != "" {
+			return NewClassifier(c.Path), nil
+		}
+		return NewClassifier(), nil
+	case Local:
+		if t != depsdev.GO && t != depsdev.NPM && t != depsdev.CARGO {
+			return nil, nil
+		}
+		if c.Path != "" {
+			return NewLocal(c.Path), nil
+		}
+		return NewLocal(), nil
+	}
+	return nil, fmt.Errorf("unknown license provider %q, expected %s, %s, %s, %s or %s", c.Name, Local, DepsDev, Registry, ClearlyDefined, Classifier)
+}
+
+// registryOf is the registry of the ecosystem of a dep type, or nil for ecosystems without one depot knows. Registries
+// are requested as deps.dev is, with its timeout, proxy, user agent, retries and rate limit, per host.
+func registryOf(depType depsdev.DepType, uri string, client *fetch.Client) LicenseProvider {
+	switch depType {
+	case depsdev.NPM:
+		c := registry.NewNPM().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.PYPI:
+		c := registry.NewPyPI().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.CARGO:
+		c := registry.NewCrates().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.MAVEN:
+		c := registry.NewMavenCentral().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.RUBYGEMS:
+		c := registry.NewRubyGems().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.SWIFT, depsdev.ACTIONS:
+		c := registry.NewGitHub().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.PUB:
+		c := registry.NewPubDev().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.HEX:
+		c := registry.NewHex().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.TERRAFORM:
+		c := registry.NewTerraform().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	case depsdev.HELM:
+		c := registry.NewArtifactHub().WithFetch(client)
+		return withURL(c, c.WithURL, uri)
+	}
+	return nil
+}
+
+// withURL points a provider at the url configured for it, if any
+func withURL[P LicenseProvider](p P, with func(string) P, uri string) LicenseProvider {
+	if uri == "" {
+		return p
+	}
+	return with(uri)
+}
+
+// WithProviders replaces the license providers, and those cross-checking them, by those configured in .depot.yml
+func (pro *Processor) WithProviders(config []depot.Provider) (*Processor, error) {
+	chains, err := Chains(config, pro.depsDev)
+	if err != nil {
+		return nil, err
+	}
+	checkers, err := Checkers(config, pro.depsDev)
+	if err != nil {
+		return nil, err
+	}
+	pro.config = config
+	pro.providers = chains
+	pro.checkers = checkers
+	return pro, nil
+}
+
+// DepsDevClient is a deps.dev client configured as in .depot.yml, and by flags. The registries are requested as
+// it is configured.
+func DepsDevClient(c depot.DepsDev) (*depsdev.Client, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = fetch.DefaultTimeout
+	}
+	httpClient, err := fetch.NewHTTPClient(timeout, c.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	f := fetch.New().WithHTTPClient(httpClient)
+	if c.UserAgent != "" {
+		f.WithUserAgent(c.UserAgent)
+	}
+	if c.Retries != nil {
+		f.WithRetries(*c.Retries)
+	}
+	if c.RateLimit != 0 {
+		f.WithRateLimit(c.RateLimit)
+	}
+	client := depsdev.New().WithFetch(f)
+	if c.URL != "" {
+		client.WithURL(c.URL)
+	}
+	return client, nil
+}
+
+// WithDepsDev asks deps.dev with the given client, e.g. one configured with a timeout and proxy, or pointed
+// at a fake deps.dev in tests, in the providers configured so far
+func (pro *Processor) WithDepsDev(client *depsdev.Client) (*Processor, error) {
+	pro.depsDev = client
+	return pro.WithProviders(pro.config)
+}
+
+// WithProvider replaces the license providers of a dep type by a single one
+func (pro *Processor) WithProvider(depType depsdev.DepType, provider LicenseProvider) *Processor {
+	pro.providers[depType] = []LicenseProvider{provider}
+	return pro
+}
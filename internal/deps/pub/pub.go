@@ -0,0 +1,112 @@
+package pub
+
+import (
+	"gopkg.in/yaml.v3"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LockFile is a dart or flutter pubspec.lock
+type LockFile struct {
+	Packages map[string]Package `yaml:"packages"`
+}
+
+type Package struct {
+	// Dependency is "direct main", "direct dev", "direct overridden" or transitive
+	Dependency string `yaml:"dependency"`
+	// Description is the package name for sdk packages, otherwise a map with name, url, path or resolved-ref
+	Description any `yaml:"description"`
+	// Source is hosted, git, path or sdk
+	Source  string `yaml:"source"`
+	Version string `yaml:"version"`
+}
+
+func (p Package) Direct() bool {
+	return strings.HasPrefix(p.Dependency, "direct")
+}
+
+func (p Package) Dev() bool {
+	return p.Dependency == "direct dev"
+}
+
+// Local is true for path and sdk packages, the project's own and those shipped with dart or flutter
+func (p Package) Local() bool {
+	return p.Source == "path" || p.Source == "sdk"
+}
+
+func (p Package) description(key string) string {
+	d, ok := p.Description.(map[string]any)
+	if !ok {
+		return ""
+	}
+	s, _ := d[key].(string)
+	return s
+}
+
+// URL is the package repository hosted packages are from, or the repository of git packages
+func (p Package) URL() string {
+	return p.description("url")
+}
+
+// Ref is the commit git packages are locked to
+func (p Package) Ref() string {
+	return p.description("resolved-ref")
+}
+
+// PubDev is true for packages hosted on the public pub.dev repository
+func (p Package) PubDev() bool {
+	if p.Source != "hosted" {
+		return false
+	}
+	u, err := url.Parse(p.URL())
+	if err != nil {
+		return false
+	}
+	return u.Host == "pub.dev" || u.Host == "pub.dartlang.org"
+}
+
+func ReadLockFile(path string) (LockFile, error) {
+	var l LockFile
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return l, err
+	}
+	err = yaml.Unmarshal(b, &l)
+	return l, err
+}
+
+// Cache is where pub keeps downloaded packages, $PUB_CACHE or ~/.pub-cache
+func Cache() string {
+	if cache := os.Getenv("PUB_CACHE"); cache != "" {
+		return cache
+	}
+	dir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, ".pub-cache")
+}
+
+// FindPackage locates a package in the pub cache, hosted/<host>/<name>-<version> for hosted packages
+// and git/<name>-<ref> for git packages
+func FindPackage(cache string, name string, p Package) (string, bool) {
+	var pattern string
+	switch p.Source {
+	case "hosted":
+		pattern = filepath.Join(cache, "hosted", "*", name+"-"+p.Version)
+	case "git":
+		if p.Ref() == "" {
+			return "", false
+		}
+		pattern = filepath.Join(cache, "git", name+"-"+p.Ref()+"*")
+	default:
+		return "", false
+	}
+	matches, _ := filepath.Glob(pattern)
+	if len(matches) == 0 {
+		return "", false
+	}
+	return matches[0], true
+}
@@ -0,0 +1,69 @@
+package pub
+
+import (
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLockFile(t *testing.T) {
+	lock, err := ReadLockFile("./testdata/pubspec.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := slicez.Map(slicez.Sort(mapz.Keys(lock.Packages)), func(name string) string {
+		p := lock.Packages[name]
+		s := name + " " + p.Version
+		switch {
+		case p.Local():
+			s += " local"
+		case p.Dev():
+			s += " dev"
+		case p.Direct():
+			s += " direct"
+		}
+		if p.PubDev() {
+			s += " pub.dev"
+		}
+		return s
+	})
+	want := []string{
+		"async 2.11.0 pub.dev",
+		"flutter 0.0.0 local",
+		"http 1.1.0 direct pub.dev",
+		"lints 2.1.1 dev pub.dev",
+		"widgets 0.2.0 direct",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestFindPackage(t *testing.T) {
+	lock, err := ReadLockFile("./testdata/pubspec.lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache := t.TempDir()
+	for _, dir := range []string{
+		filepath.Join(cache, "hosted", "pub.dev", "http-1.1.0"),
+		filepath.Join(cache, "git", "widgets-4f8c5c3d5e0e1c8b5a1e7d2c3b4a5f6e7d8c9b0a"),
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"http", "widgets"} {
+		if _, found := FindPackage(cache, name, lock.Packages[name]); !found {
+			t.Fatalf("expected to find %s in the pub cache", name)
+		}
+	}
+	if _, found := FindPackage(cache, "async", lock.Packages["async"]); found {
+		t.Fatal("did not expect to find async in the pub cache")
+	}
+}
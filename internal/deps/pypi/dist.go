@@ -0,0 +1,195 @@
+package pypi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/modfin/depot/internal/spdx"
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	"io"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Distribution is a package installed in a python environment, as described by its .dist-info directory
+// ref. https://packaging.python.org/en/latest/specifications/recording-installed-packages/
+type Distribution struct {
+	Name    string // PEP 503 normalized
+	Version string // PEP 440 normalized
+	Path    string
+
+	License           string
+	LicenseExpression string
+	Classifiers       []string
+	// LicenseFiles are the license files listed in RECORD, by path relative to site-packages
+	LicenseFiles map[string]string
+
+	// Requires are the normalized names of the distributions it depends on, not counting extras
+	Requires []string
+	// Requested is true when the distribution was installed on its own, not as a dependency of another
+	Requested bool
+	// Local is true when the distribution was installed from a directory, e.g. pip install -e .
+	Local bool
+}
+
+// Distributions reads the metadata of all packages installed in a python environment
+func Distributions(env string) ([]Distribution, error) {
+	dirs, err := SitePackages(env)
+	if err != nil {
+		return nil, err
+	}
+
+	var dists []Distribution
+	for _, dir := range dirs {
+		infos, err := filepath.Glob(filepath.Join(dir, "*.dist-info"))
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			d, err := ReadDistribution(info)
+			if err != nil {
+				return nil, err
+			}
+			dists = append(dists, d)
+		}
+	}
+	return dists, nil
+}
+
+var markerExtra = regexp.MustCompile(`\bextra\s*==`)
+
+// ReadDistribution reads an installed package from its .dist-info directory
+func ReadDistribution(path string) (Distribution, error) {
+	d := Distribution{
+		Path:         path,
+		LicenseFiles: map[string]string{},
+	}
+
+	b, err := os.ReadFile(filepath.Join(path, "METADATA"))
+	if err != nil {
+		return d, err
+	}
+	// METADATA is in email header format, the description may follow after a blank line
+	headers, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(b))).ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) && headers.Get("Name") == "" {
+		return d, fmt.Errorf("could not read %s: %w", filepath.Join(path, "METADATA"), err)
+	}
+
+	d.Name = Normalize(headers.Get("Name"))
+	d.Version = NormalizeVersion(headers.Get("Version"))
+	d.License = strings.TrimSpace(headers.Get("License"))
+	d.LicenseExpression = strings.TrimSpace(headers.Get("License-Expression"))
+	d.Classifiers = slicez.Filter(headers.Values("Classifier"), func(c string) bool {
+		return strings.HasPrefix(c, "License ::")
+	})
+	for _, req := range headers.Values("Requires-Dist") {
+		r, err := parseRequirement(req)
+		if err != nil || r.Name == "" || markerExtra.MatchString(r.Marker) {
+			continue
+		}
+		d.Requires = append(d.Requires, Normalize(r.Name))
+	}
+
+	_, err = os.Stat(filepath.Join(path, "REQUESTED"))
+	d.Requested = err == nil
+
+	if b, err := os.ReadFile(filepath.Join(path, "direct_url.json")); err == nil {
+		var direct struct {
+			URL     string          `json:"url"`
+			DirInfo json.RawMessage `json:"dir_info"`
+		}
+		if json.Unmarshal(b, &direct) == nil {
+			d.Local = strings.HasPrefix(direct.URL, "file:") && direct.DirInfo != nil
+		}
+	}
+
+	licenseFiles, err := recordedLicenseFiles(path)
+	if err != nil {
+		return d, err
+	}
+	sitePackages := filepath.Dir(path)
+	for _, f := range licenseFiles {
+		text, err := os.ReadFile(filepath.Join(sitePackages, f))
+		if err != nil {
+			continue
+		}
+		d.LicenseFiles[f] = string(text)
+	}
+
+	return d, nil
+}
+
+var licenseFileName = regexp.MustCompile(`(?i)^(licen[cs]e|copying|notice)`)
+
+// recordedLicenseFiles lists the license files in RECORD that belong to the dist-info directory,
+// where wheels put them, in the root or a licenses/ sub directory per PEP 639
+func recordedLicenseFiles(path string) ([]string, error) {
+	f, err := os.Open(filepath.Join(path, "RECORD"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	info := filepath.Base(path) + "/"
+	var files []string
+	for _, row := range rows {
+		if len(row) == 0 {
+			continue
+		}
+		file := filepath.ToSlash(row[0])
+		if !strings.HasPrefix(file, info) {
+			continue
+		}
+		if strings.HasPrefix(file, info+"licenses/") || licenseFileName.MatchString(filepath.Base(file)) {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// SPDX returns the licenses a distribution declares, preferring the PEP 639 License-Expression,
+// then classifiers, the License field and last the contents of its license files
+func (d Distribution) SPDX() []string {
+	if d.LicenseExpression != "" {
+		return []string{d.LicenseExpression}
+	}
+
+	var ids []string
+	for _, c := range d.Classifiers {
+		if id, ok := spdx.FromClassifier(c); ok {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) > 0 {
+		return slicez.Uniq(ids)
+	}
+
+	// The License field is free text, at times the whole license, we can only look up short names
+	if id, ok := spdx.FromName(d.License); ok {
+		return []string{id}
+	}
+
+	for _, name := range slicez.Sort(mapz.Keys(d.LicenseFiles)) {
+		if id, ok := spdx.FromText(d.LicenseFiles[name]); ok {
+			ids = append(ids, id)
+		}
+	}
+	return slicez.Uniq(ids)
+}
@@ -0,0 +1,75 @@
+package pypi
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDistributions(t *testing.T) {
+	env := writeFiles(t, map[string]string{
+		"pyvenv.cfg": "home = /usr/bin\n",
+		"lib/python3.12/site-packages/requests-2.31.0.dist-info/METADATA": `Metadata-Version: 2.1
+Name: requests
+Version: 2.31.0
+License: Apache 2.0
+Classifier: License :: OSI Approved :: Apache Software License
+Requires-Dist: idna (<4,>=2.5)
+Requires-Dist: PySocks (!=1.5.7,>=1.5.6) ; extra == 'socks'
+
+Requests is an HTTP library.
+`,
+		"lib/python3.12/site-packages/requests-2.31.0.dist-info/REQUESTED": "",
+		"lib/python3.12/site-packages/idna-3.6.dist-info/METADATA": `Metadata-Version: 2.1
+Name: idna
+Version: 3.6
+`,
+		"lib/python3.12/site-packages/idna-3.6.dist-info/RECORD": `idna/__init__.py,sha256=abc,849
+idna-3.6.dist-info/LICENSE.md,sha256=def,1541
+idna-3.6.dist-info/METADATA,sha256=ghi,9888
+`,
+		"lib/python3.12/site-packages/idna-3.6.dist-info/LICENSE.md": `BSD 3-Clause License
+
+Redistribution and use in source and binary forms, with or without modification, are permitted provided that...
+3. Neither the name of the copyright holder nor the names of its contributors may be used
+`,
+		"lib/python3.12/site-packages/attrs-23.2.0.dist-info/METADATA": `Metadata-Version: 2.4
+Name: attrs
+Version: 23.2.0
+License-Expression: MIT
+`,
+	})
+
+	dists, err := Distributions(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dists) != 3 {
+		t.Fatalf("expected 3 distributions, got %d", len(dists))
+	}
+
+	byName := map[string]Distribution{}
+	for _, d := range dists {
+		byName[d.Name] = d
+	}
+
+	requests := byName["requests"]
+	if !requests.Requested || len(requests.Requires) != 1 || requests.Requires[0] != "idna" {
+		t.Fatalf("unexpected requests distribution %+v", requests)
+	}
+
+	want := map[string]string{"requests": "Apache-2.0", "idna": "BSD-3-Clause", "attrs": "MIT"}
+	for name, license := range want {
+		l := byName[name].SPDX()
+		if len(l) != 1 || l[0] != license {
+			t.Fatalf("expected %s to be %s, got %v", name, license, l)
+		}
+	}
+
+	installed, err := Installed(filepath.Join(env, "lib/python3.12/site-packages"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if installed["idna"] != "3.6" {
+		t.Fatalf("expected idna 3.6 to be installed, got %v", installed)
+	}
+}
@@ -0,0 +1,68 @@
+package pypi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SitePackages finds the site-packages directories of a python environment. The environment
+// may be given as a virtualenv, a python prefix such as /usr/local, or a site-packages directory itself.
+func SitePackages(env string) ([]string, error) {
+	info, err := os.Stat(env)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("python environment %s is not a directory", env)
+	}
+
+	if metadata, _ := filepath.Glob(filepath.Join(env, "*.dist-info")); len(metadata) > 0 {
+		return []string{env}, nil
+	}
+
+	var dirs []string
+	for _, pattern := range []string{"lib/python*/site-packages", "lib64/python*/site-packages", "lib/python*/dist-packages", "Lib/site-packages"} {
+		matches, _ := filepath.Glob(filepath.Join(env, pattern))
+		dirs = append(dirs, matches...)
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("could not find any site-packages in %s", env)
+	}
+	return dirs, nil
+}
+
+// Installed lists the versions of the packages installed in a python environment, by normalized name
+func Installed(env string) (map[string]string, error) {
+	dirs, err := SitePackages(env)
+	if err != nil {
+		return nil, err
+	}
+
+	installed := map[string]string{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			var base string
+			switch {
+			case strings.HasSuffix(e.Name(), ".dist-info"):
+				base = strings.TrimSuffix(e.Name(), ".dist-info")
+			case strings.HasSuffix(e.Name(), ".egg-info"):
+				base = strings.TrimSuffix(e.Name(), ".egg-info")
+			default:
+				continue
+			}
+			// {name}-{version}[-pyX.Y], where - in name and version are escaped as _
+			parts := strings.Split(base, "-")
+			if len(parts) < 2 {
+				continue
+			}
+			installed[Normalize(parts[0])] = parts[1]
+		}
+	}
+	return installed, nil
+}
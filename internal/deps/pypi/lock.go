@@ -0,0 +1,366 @@
+package pypi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/modfin/henry/exp/containerz/set"
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Locked is a package pinned in a python lockfile, poetry.lock, Pipfile.lock, pdm.lock or uv.lock
+type Locked struct {
+	Name    string // PEP 503 normalized
+	Version string // PEP 440 normalized
+
+	// Source is where the package is locked from when it is not the package index, e.g. a git url
+	Source string
+	// Local is true for the project itself, workspace members and path dependencies
+	Local bool
+
+	Direct bool
+	// Dev is true for packages only needed by development groups
+	Dev bool
+	// Extras are the optional dependency groups a package is only needed by
+	Extras []string
+
+	dependencies []string
+	groups       []string // as stated by the lockfile, main for the default group
+}
+
+// ReadLockfile reads any of the python lockfiles we know, by file name,
+// with directness and groups from the pyproject.toml or Pipfile next to it
+func ReadLockfile(path string) ([]Locked, error) {
+	switch strings.ToLower(filepath.Base(path)) {
+	case "poetry.lock":
+		return ReadPoetryLock(path)
+	case "pipfile.lock":
+		return ReadPipfileLock(path)
+	case "pdm.lock":
+		return ReadPDMLock(path)
+	case "uv.lock":
+		return ReadUVLock(path)
+	}
+	return nil, fmt.Errorf("%s is not a python lockfile", path)
+}
+
+type poetryLock struct {
+	Package []struct {
+		Name         string         `toml:"name"`
+		Version      string         `toml:"version"`
+		Category     string         `toml:"category"`
+		Groups       []string       `toml:"groups"`
+		Optional     bool           `toml:"optional"`
+		Dependencies map[string]any `toml:"dependencies"`
+		Source       struct {
+			Type              string `toml:"type"`
+			URL               string `toml:"url"`
+			ResolvedReference string `toml:"resolved_reference"`
+		} `toml:"source"`
+	} `toml:"package"`
+}
+
+func ReadPoetryLock(path string) ([]Locked, error) {
+	var lock poetryLock
+	if _, err := toml.DecodeFile(path, &lock); err != nil {
+		return nil, err
+	}
+
+	var pkgs []Locked
+	for _, p := range lock.Package {
+		l := Locked{
+			Name:         Normalize(p.Name),
+			Version:      NormalizeVersion(p.Version),
+			dependencies: slicez.Map(mapz.Keys(p.Dependencies), Normalize),
+		}
+		switch p.Source.Type {
+		case "", "legacy":
+		case "directory", "file":
+			l.Local = true
+		case "git":
+			l.Source = fmt.Sprintf("git+%s@%s", p.Source.URL, p.Source.ResolvedReference)
+		default:
+			l.Source = p.Source.URL
+		}
+
+		// Packages only installed through extras are in the main group, their extras are found through the graph
+		if !p.Optional {
+			switch {
+			case len(p.Groups) > 0:
+				l.groups = p.Groups
+			case p.Category != "":
+				l.groups = []string{p.Category}
+			}
+		}
+		pkgs = append(pkgs, l)
+	}
+
+	roots, err := pyprojectRoots(path)
+	if err != nil {
+		return nil, err
+	}
+	return label(pkgs, roots), nil
+}
+
+type pipfileLockEntry struct {
+	Version  string `json:"version"`
+	Git      string `json:"git"`
+	Ref      string `json:"ref"`
+	Path     string `json:"path"`
+	File     string `json:"file"`
+	Editable bool   `json:"editable"`
+}
+
+type pipfileLock struct {
+	Default map[string]pipfileLockEntry `json:"default"`
+	Develop map[string]pipfileLockEntry `json:"develop"`
+}
+
+func ReadPipfileLock(path string) ([]Locked, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lock pipfileLock
+	if err := json.Unmarshal(b, &lock); err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*Locked{}
+	add := func(section map[string]pipfileLockEntry, group string) {
+		for _, name := range slicez.Sort(mapz.Keys(section)) {
+			e := section[name]
+			n := Normalize(name)
+			if byName[n] == nil {
+				l := &Locked{
+					Name:    n,
+					Version: NormalizeVersion(strings.TrimPrefix(e.Version, "==")),
+				}
+				switch {
+				case e.Path != "" || strings.HasPrefix(e.File, "file:"):
+					l.Local = true
+				case e.Git != "":
+					l.Source = fmt.Sprintf("git+%s@%s", e.Git, e.Ref)
+					if l.Version == "" {
+						l.Version = e.Ref
+					}
+				case e.File != "":
+					l.Source = e.File
+				}
+				byName[n] = l
+			}
+			byName[n].groups = append(byName[n].groups, group)
+		}
+	}
+	add(lock.Default, "main")
+	add(lock.Develop, "develop")
+
+	var roots Roots
+	pipfile, err := ReadPipfile(filepath.Join(filepath.Dir(path), "Pipfile"))
+	if err == nil {
+		roots = pipfile.Roots()
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	pkgs := slicez.Map(slicez.Sort(mapz.Keys(byName)), func(name string) Locked {
+		return *byName[name]
+	})
+	return label(pkgs, roots), nil
+}
+
+type pdmLock struct {
+	Package []struct {
+		Name         string   `toml:"name"`
+		Version      string   `toml:"version"`
+		Groups       []string `toml:"groups"`
+		Dependencies []string `toml:"dependencies"`
+		Git          string   `toml:"git"`
+		Revision     string   `toml:"revision"`
+		Path         string   `toml:"path"`
+		URL          string   `toml:"url"`
+	} `toml:"package"`
+}
+
+func ReadPDMLock(path string) ([]Locked, error) {
+	var lock pdmLock
+	if _, err := toml.DecodeFile(path, &lock); err != nil {
+		return nil, err
+	}
+
+	var pkgs []Locked
+	for _, p := range lock.Package {
+		l := Locked{
+			Name:         Normalize(p.Name),
+			Version:      NormalizeVersion(p.Version),
+			dependencies: requirementNames(p.Dependencies),
+			groups: slicez.Map(p.Groups, func(g string) string {
+				if g == "default" {
+					return "main"
+				}
+				return g
+			}),
+		}
+		switch {
+		case p.Path != "":
+			l.Local = true
+		case p.Git != "":
+			l.Source = fmt.Sprintf("git+%s@%s", p.Git, p.Revision)
+		case p.URL != "":
+			l.Source = p.URL
+		}
+		pkgs = append(pkgs, l)
+	}
+
+	roots, err := pyprojectRoots(path)
+	if err != nil {
+		return nil, err
+	}
+	return label(pkgs, roots), nil
+}
+
+type uvDependency struct {
+	Name string `toml:"name"`
+}
+
+type uvLock struct {
+	Package []struct {
+		Name                 string                    `toml:"name"`
+		Version              string                    `toml:"version"`
+		Source               map[string]any            `toml:"source"`
+		Dependencies         []uvDependency            `toml:"dependencies"`
+		OptionalDependencies map[string][]uvDependency `toml:"optional-dependencies"`
+		DevDependencies      map[string][]uvDependency `toml:"dev-dependencies"`
+	} `toml:"package"`
+}
+
+// ReadUVLock reads uv.lock, where the roots are the workspace members in the lock itself
+func ReadUVLock(path string) ([]Locked, error) {
+	var lock uvLock
+	if _, err := toml.DecodeFile(path, &lock); err != nil {
+		return nil, err
+	}
+
+	names := func(deps []uvDependency) []string {
+		return slicez.Map(deps, func(d uvDependency) string {
+			return Normalize(d.Name)
+		})
+	}
+
+	roots := Roots{
+		Optional: map[string][]string{},
+		Dev:      map[string][]string{},
+	}
+	var pkgs []Locked
+	for _, p := range lock.Package {
+		l := Locked{
+			Name:         Normalize(p.Name),
+			Version:      NormalizeVersion(p.Version),
+			dependencies: names(p.Dependencies),
+		}
+		for extra, deps := range p.OptionalDependencies {
+			l.dependencies = append(l.dependencies, names(deps)...)
+			if isUVLocal(p.Source) {
+				roots.Optional[extra] = append(roots.Optional[extra], names(deps)...)
+			}
+		}
+
+		switch {
+		case isUVLocal(p.Source):
+			l.Local = true
+			roots.Main = append(roots.Main, names(p.Dependencies)...)
+			for group, deps := range p.DevDependencies {
+				roots.Dev[group] = append(roots.Dev[group], names(deps)...)
+			}
+		case p.Source["git"] != nil:
+			l.Source = fmt.Sprintf("git+%v", p.Source["git"])
+		case p.Source["url"] != nil:
+			l.Source = fmt.Sprintf("%v", p.Source["url"])
+		}
+		pkgs = append(pkgs, l)
+	}
+	return label(pkgs, roots), nil
+}
+
+func isUVLocal(source map[string]any) bool {
+	for _, kind := range []string{"editable", "virtual", "directory", "path"} {
+		if source[kind] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func pyprojectRoots(lockfile string) (Roots, error) {
+	project, err := ReadPyProject(filepath.Join(filepath.Dir(lockfile), "pyproject.toml"))
+	if os.IsNotExist(err) {
+		return Roots{}, nil
+	}
+	if err != nil {
+		return Roots{}, err
+	}
+	return project.Roots(), nil
+}
+
+// label decides directness and groups of the locked packages. Groups stated by the lockfile
+// are used as they are, otherwise they are found by walking the dependency graph from the roots.
+func label(pkgs []Locked, roots Roots) []Locked {
+	graph := map[string][]string{}
+	for _, p := range pkgs {
+		graph[p.Name] = append(graph[p.Name], p.dependencies...)
+	}
+	reach := func(from []string) set.Set[string] {
+		seen := set.New[string]()
+		queue := from
+		for len(queue) > 0 {
+			name := queue[0]
+			queue = queue[1:]
+			if seen.Exists(name) {
+				continue
+			}
+			seen.Add(name)
+			queue = append(queue, graph[name]...)
+		}
+		return seen
+	}
+
+	main := reach(roots.Main)
+	dev := reach(slicez.Flatten(mapz.Values(roots.Dev)))
+	extras := map[string]set.Set[string]{}
+	for extra, names := range roots.Optional {
+		extras[extra] = reach(names)
+	}
+
+	direct := set.From(roots.All()...)
+
+	for i, p := range pkgs {
+		p.Direct = direct.Exists(p.Name)
+
+		if len(p.groups) > 0 {
+			if !slicez.Contains(p.groups, "main") {
+				p.Extras = slicez.Filter(p.groups, func(g string) bool {
+					return roots.Optional[g] != nil
+				})
+				p.Dev = len(p.Extras) == 0
+			}
+			pkgs[i] = p
+			continue
+		}
+
+		switch {
+		case main.Exists(p.Name):
+		case slicez.SomeFunc(mapz.Values(extras), func(s set.Set[string]) bool { return s.Exists(p.Name) }):
+			p.Extras = slicez.Sort(slicez.Filter(mapz.Keys(extras), func(extra string) bool {
+				return extras[extra].Exists(p.Name)
+			}))
+		case dev.Exists(p.Name):
+			p.Dev = true
+		}
+		pkgs[i] = p
+	}
+	return pkgs
+}
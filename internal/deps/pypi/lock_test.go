@@ -0,0 +1,154 @@
+package pypi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+type lockedWant struct {
+	version string
+	direct  bool
+	dev     bool
+	extras  string
+}
+
+func checkLocked(t *testing.T, pkgs []Locked, wants map[string]lockedWant) {
+	if len(pkgs) != len(wants) {
+		t.Fatalf("expected %d packages, got %d: %+v", len(wants), len(pkgs), pkgs)
+	}
+	for _, p := range pkgs {
+		w, ok := wants[p.Name]
+		if !ok {
+			t.Fatalf("unexpected package %s", p.Name)
+		}
+		extras := ""
+		for _, e := range p.Extras {
+			extras += e
+		}
+		if p.Version != w.version || p.Direct != w.direct || p.Dev != w.dev || extras != w.extras {
+			t.Fatalf("expected %s to be %+v, got %+v", p.Name, w, p)
+		}
+	}
+}
+
+func TestPoetryLock(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"pyproject.toml": `
+[tool.poetry.dependencies]
+python = "^3.11"
+Requests = "^2.31"
+PySocks = { version = "^1.7", optional = true }
+
+[tool.poetry.extras]
+socks = ["pysocks"]
+
+[tool.poetry.group.test.dependencies]
+pytest = "^7"
+`,
+		"poetry.lock": `
+[[package]]
+name = "requests"
+version = "2.31.0"
+optional = false
+
+[package.dependencies]
+idna = ">=2.5,<4"
+
+[[package]]
+name = "idna"
+version = "3.6"
+optional = false
+
+[[package]]
+name = "PySocks"
+version = "1.7.1"
+optional = true
+
+[[package]]
+name = "pytest"
+version = "7.4.3"
+optional = false
+
+[package.dependencies]
+iniconfig = "*"
+
+[[package]]
+name = "iniconfig"
+version = "2.0.0"
+optional = false
+`,
+	})
+
+	pkgs, err := ReadLockfile(filepath.Join(dir, "poetry.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLocked(t, pkgs, map[string]lockedWant{
+		"requests":  {version: "2.31.0", direct: true},
+		"idna":      {version: "3.6"},
+		"pysocks":   {version: "1.7.1", direct: true, extras: "socks"},
+		"pytest":    {version: "7.4.3", direct: true, dev: true},
+		"iniconfig": {version: "2.0.0", dev: true},
+	})
+}
+
+func TestUVLock(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"uv.lock": `
+version = 1
+
+[[package]]
+name = "app"
+version = "0.1.0"
+source = { editable = "." }
+dependencies = [{ name = "requests" }]
+
+[package.dev-dependencies]
+dev = [{ name = "ruff" }]
+
+[[package]]
+name = "requests"
+version = "2.31.0"
+source = { registry = "https://pypi.org/simple" }
+dependencies = [{ name = "certifi" }]
+
+[[package]]
+name = "certifi"
+version = "2023.11.17"
+source = { registry = "https://pypi.org/simple" }
+
+[[package]]
+name = "ruff"
+version = "0.1.8"
+source = { registry = "https://pypi.org/simple" }
+`,
+	})
+
+	pkgs, err := ReadLockfile(filepath.Join(dir, "uv.lock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pkgs[0].Local {
+		t.Fatalf("expected the project itself to be local")
+	}
+	checkLocked(t, pkgs[1:], map[string]lockedWant{
+		"requests": {version: "2.31.0", direct: true},
+		"certifi":  {version: "2023.11.17"},
+		"ruff":     {version: "0.1.8", direct: true, dev: true},
+	})
+}
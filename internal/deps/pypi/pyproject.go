@@ -0,0 +1,251 @@
+package pypi
+
+import (
+	"github.com/BurntSushi/toml"
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	"regexp"
+	"strings"
+)
+
+// PyProject is the parts of pyproject.toml declaring dependencies, for PEP 621 projects and poetry, pdm and uv
+type PyProject struct {
+	Project struct {
+		Name                 string              `toml:"name"`
+		Dependencies         []string            `toml:"dependencies"`
+		OptionalDependencies map[string][]string `toml:"optional-dependencies"`
+	} `toml:"project"`
+
+	// PEP 735, entries are requirement strings or {include-group = "..."} tables
+	DependencyGroups map[string][]any `toml:"dependency-groups"`
+
+	Tool struct {
+		Poetry struct {
+			Dependencies    map[string]any `toml:"dependencies"`
+			DevDependencies map[string]any `toml:"dev-dependencies"`
+			Group           map[string]struct {
+				Dependencies map[string]any `toml:"dependencies"`
+			} `toml:"group"`
+			Extras map[string][]string `toml:"extras"`
+		} `toml:"poetry"`
+		PDM struct {
+			DevDependencies map[string][]string `toml:"dev-dependencies"`
+		} `toml:"pdm"`
+		UV struct {
+			DevDependencies []string `toml:"dev-dependencies"`
+		} `toml:"uv"`
+	} `toml:"tool"`
+}
+
+// Pipfile is the parts of a pipenv Pipfile declaring dependencies
+type Pipfile struct {
+	Packages    map[string]any `toml:"packages"`
+	DevPackages map[string]any `toml:"dev-packages"`
+}
+
+// Roots are the packages a project depends on directly, by normalized name
+type Roots struct {
+	Main []string
+	// Optional are the extras, optional dependencies, of the project by extra name
+	Optional map[string][]string
+	// Dev are development only dependency groups by group name
+	Dev map[string][]string
+}
+
+func (r Roots) All() []string {
+	all := r.Main
+	for _, names := range r.Optional {
+		all = append(all, names...)
+	}
+	for _, names := range r.Dev {
+		all = append(all, names...)
+	}
+	return slicez.Uniq(all)
+}
+
+func ReadPyProject(path string) (PyProject, error) {
+	var p PyProject
+	_, err := toml.DecodeFile(path, &p)
+	return p, err
+}
+
+func ReadPipfile(path string) (Pipfile, error) {
+	var p Pipfile
+	_, err := toml.DecodeFile(path, &p)
+	return p, err
+}
+
+func (p PyProject) Roots() Roots {
+	roots := Roots{
+		Optional: map[string][]string{},
+		Dev:      map[string][]string{},
+	}
+
+	roots.Main = requirementNames(p.Project.Dependencies)
+	for extra, reqs := range p.Project.OptionalDependencies {
+		roots.Optional[extra] = requirementNames(reqs)
+	}
+
+	for group := range p.DependencyGroups {
+		roots.Dev[group] = p.dependencyGroup(group, map[string]bool{})
+	}
+	for group, reqs := range p.Tool.PDM.DevDependencies {
+		roots.Dev[group] = append(roots.Dev[group], requirementNames(reqs)...)
+	}
+	if len(p.Tool.UV.DevDependencies) > 0 {
+		roots.Dev["dev"] = append(roots.Dev["dev"], requirementNames(p.Tool.UV.DevDependencies)...)
+	}
+
+	// poetry, where optional dependencies are only installed through the extras referring to them
+	poetry := p.Tool.Poetry
+	for name, spec := range poetry.Dependencies {
+		if name == "python" {
+			continue
+		}
+		if table, ok := spec.(map[string]any); ok && table["optional"] == true {
+			continue
+		}
+		roots.Main = append(roots.Main, Normalize(name))
+	}
+	for extra, names := range poetry.Extras {
+		roots.Optional[extra] = append(roots.Optional[extra], slicez.Map(names, Normalize)...)
+	}
+	if len(poetry.DevDependencies) > 0 {
+		roots.Dev["dev"] = append(roots.Dev["dev"], slicez.Map(mapz.Keys(poetry.DevDependencies), Normalize)...)
+	}
+	for group, g := range poetry.Group {
+		if group == "main" {
+			roots.Main = append(roots.Main, slicez.Map(mapz.Keys(g.Dependencies), Normalize)...)
+			continue
+		}
+		roots.Dev[group] = append(roots.Dev[group], slicez.Map(mapz.Keys(g.Dependencies), Normalize)...)
+	}
+
+	return roots
+}
+
+// Requirements lists the requirements of the project, leaving out development groups
+func (p PyProject) Requirements() []Requirement {
+	var reqs []Requirement
+
+	add := func(req string, optional string) {
+		r, err := parseRequirement(req)
+		if err != nil {
+			return
+		}
+		r.Optional = optional
+		reqs = append(reqs, r)
+	}
+	for _, req := range p.Project.Dependencies {
+		add(req, "")
+	}
+	for _, extra := range slicez.Sort(mapz.Keys(p.Project.OptionalDependencies)) {
+		for _, req := range p.Project.OptionalDependencies[extra] {
+			add(req, extra)
+		}
+	}
+
+	poetry := p.Tool.Poetry
+	extraOf := map[string]string{}
+	for _, extra := range slicez.Sort(mapz.Keys(poetry.Extras)) {
+		for _, name := range poetry.Extras[extra] {
+			if extraOf[Normalize(name)] == "" {
+				extraOf[Normalize(name)] = extra
+			}
+		}
+	}
+	for _, name := range slicez.Sort(mapz.Keys(poetry.Dependencies)) {
+		if name == "python" {
+			continue
+		}
+		r := poetryRequirement(name, poetry.Dependencies[name])
+		r.Optional = extraOf[Normalize(name)]
+		reqs = append(reqs, r)
+	}
+	if main, ok := poetry.Group["main"]; ok {
+		for _, name := range slicez.Sort(mapz.Keys(main.Dependencies)) {
+			reqs = append(reqs, poetryRequirement(name, main.Dependencies[name]))
+		}
+	}
+	return reqs
+}
+
+var bareVersion = regexp.MustCompile(`^[0-9]`)
+
+// poetryRequirement converts a poetry dependency, "^1.2", "1.2.3" or a table such as { version = "^1.2", optional = true }
+// or { git = "https://...", tag = "v1.2" }, into a requirement. Poetry reads a bare version as an exact one.
+func poetryRequirement(name string, spec any) Requirement {
+	r := Requirement{Name: name}
+
+	var version string
+	switch s := spec.(type) {
+	case string:
+		version = s
+	case map[string]any:
+		version, _ = s["version"].(string)
+		if git, ok := s["git"].(string); ok {
+			r.URL = "git+" + git
+			for _, ref := range []string{"rev", "tag", "branch"} {
+				if v, ok := s[ref].(string); ok {
+					r.URL += "@" + v
+					break
+				}
+			}
+		}
+		for _, kind := range []string{"path", "url"} {
+			if v, ok := s[kind].(string); ok {
+				r.URL = v
+			}
+		}
+	}
+	version = strings.TrimSpace(version)
+	switch {
+	case version == "*":
+	case bareVersion.MatchString(version):
+		r.Specifier = "==" + version
+	default:
+		r.Specifier = strings.Join(strings.Fields(version), "")
+	}
+	return r
+}
+
+func (p PyProject) dependencyGroup(group string, seen map[string]bool) []string {
+	if seen[group] {
+		return nil
+	}
+	seen[group] = true
+
+	var names []string
+	for _, entry := range p.DependencyGroups[group] {
+		switch e := entry.(type) {
+		case string:
+			names = append(names, requirementNames([]string{e})...)
+		case map[string]any:
+			if include, ok := e["include-group"].(string); ok {
+				names = append(names, p.dependencyGroup(include, seen)...)
+			}
+		}
+	}
+	return names
+}
+
+func (p Pipfile) Roots() Roots {
+	return Roots{
+		Main: slicez.Map(mapz.Keys(p.Packages), Normalize),
+		Dev: map[string][]string{
+			"develop": slicez.Map(mapz.Keys(p.DevPackages), Normalize),
+		},
+	}
+}
+
+func requirementNames(reqs []string) []string {
+	var names []string
+	for _, req := range reqs {
+		r, err := parseRequirement(req)
+		if err != nil || r.Name == "" {
+			continue
+		}
+		names = append(names, Normalize(r.Name))
+	}
+	return names
+}
@@ -0,0 +1,272 @@
+package pypi
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Requirement is a single line of a pip requirements file
+// ref. https://pip.pypa.io/en/stable/reference/requirements-file-format/
+type Requirement struct {
+	File string
+	Line int
+
+	Name      string
+	Extras    []string
+	Specifier string // e.g. ==1.2.3 or >=1.0,<2
+	Marker    string // e.g. python_version < "3.8"
+
+	URL      string // for direct references, VCS urls and archives
+	Editable bool
+
+	// Optional is the extra of the project the requirement belongs to, for requirements declared in pyproject.toml
+	Optional string
+}
+
+// Version is the exact version the requirement is pinned to, if any
+func (r Requirement) Version() (string, bool) {
+	spec := strings.TrimSpace(r.Specifier)
+	if strings.Contains(spec, ",") {
+		return "", false
+	}
+	for _, op := range []string{"===", "=="} {
+		if strings.HasPrefix(spec, op) {
+			v := strings.TrimSpace(strings.TrimPrefix(spec, op))
+			if v == "" || strings.Contains(v, "*") {
+				return "", false
+			}
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Local is true for requirements pointing at a directory or archive on disk, e.g. -e . or ./libs/foo
+func (r Requirement) Local() bool {
+	if r.URL == "" {
+		return false
+	}
+	return strings.HasPrefix(r.URL, "file:") || !strings.Contains(r.URL, "://") && !strings.HasPrefix(r.URL, "git+")
+}
+
+// Requirements is the result of reading a requirements file with all -r includes followed
+type Requirements struct {
+	Requirements []Requirement
+	// Constraints are read from -c files, they pin versions without adding requirements
+	Constraints []Requirement
+}
+
+// Constraint returns the version a constraint file pins the package to
+func (r Requirements) Constraint(name string) (string, bool) {
+	for _, c := range r.Constraints {
+		if Normalize(c.Name) != Normalize(name) {
+			continue
+		}
+		if v, ok := c.Version(); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// ReadRequirements reads a requirements file, following -r and -c includes relative to the including file
+func ReadRequirements(path string) (Requirements, error) {
+	var reqs Requirements
+	err := readRequirements(path, false, map[string]bool{}, &reqs)
+	return reqs, err
+}
+
+var includeRegexp = regexp.MustCompile(`^(-r|--requirement|-c|--constraint)(\s*=\s*|\s+)(\S+)$`)
+
+func readRequirements(path string, constraint bool, seen map[string]bool, reqs *Requirements) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if seen[abs] {
+		return nil
+	}
+	seen[abs] = true
+
+	lines, err := logicalLines(path)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		line := l.text
+
+		if m := includeRegexp.FindStringSubmatch(line); m != nil {
+			include := m[3]
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(path), include)
+			}
+			err := readRequirements(include, constraint || m[1] == "-c" || m[1] == "--constraint", seen, reqs)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", path, l.number, err)
+			}
+			continue
+		}
+
+		editable := false
+		for _, prefix := range []string{"-e ", "--editable ", "--editable="} {
+			if strings.HasPrefix(line, prefix) {
+				editable = true
+				line = strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			}
+		}
+
+		// Any other option, e.g. --index-url, --hash on a line of its own or -f
+		if strings.HasPrefix(line, "-") {
+			continue
+		}
+
+		r, err := parseRequirement(line)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", path, l.number, err)
+		}
+		r.File = path
+		r.Line = l.number
+		r.Editable = editable
+
+		if constraint {
+			reqs.Constraints = append(reqs.Constraints, r)
+			continue
+		}
+		reqs.Requirements = append(reqs.Requirements, r)
+	}
+	return nil
+}
+
+type logicalLine struct {
+	number int
+	text   string
+}
+
+// logicalLines joins \ continuations and strips comments and per requirement options, such as --hash
+func logicalLines(path string) ([]logicalLine, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []logicalLine
+	var current *logicalLine
+
+	scanner := bufio.NewScanner(f)
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := scanner.Text()
+
+		// comments start at # first on the line or preceded by whitespace
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			text = ""
+		}
+		if i := strings.Index(text, " #"); i >= 0 {
+			text = text[:i]
+		}
+		if i := strings.Index(text, "\t#"); i >= 0 {
+			text = text[:i]
+		}
+
+		continued := strings.HasSuffix(strings.TrimRightFunc(text, unicode.IsSpace), `\`)
+		text = strings.TrimSuffix(strings.TrimRightFunc(text, unicode.IsSpace), `\`)
+
+		if current == nil {
+			current = &logicalLine{number: number}
+		}
+		current.text += " " + text
+
+		if continued {
+			continue
+		}
+		current.text = stripOptions(strings.TrimSpace(current.text))
+		if current.text != "" {
+			lines = append(lines, *current)
+		}
+		current = nil
+	}
+	if current != nil {
+		current.text = stripOptions(strings.TrimSpace(current.text))
+		if current.text != "" {
+			lines = append(lines, *current)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+var perRequirementOptions = regexp.MustCompile(`\s+--(hash|global-option|config-settings)(=|\s+)\S+`)
+
+func stripOptions(line string) string {
+	return strings.TrimSpace(perRequirementOptions.ReplaceAllString(line, ""))
+}
+
+var nameRegexp = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)\s*(\[([^\]]*)\])?\s*(.*)$`)
+var eggRegexp = regexp.MustCompile(`[#&]egg=([A-Za-z0-9][A-Za-z0-9._-]*)`)
+
+// parseRequirement parses a PEP 508 requirement, or a pip url requirement
+func parseRequirement(line string) (Requirement, error) {
+	var r Requirement
+
+	if i := strings.Index(line, ";"); i >= 0 {
+		// a ; inside a url would need to be preceded by whitespace to be a marker, pip's rule
+		if !strings.Contains(line[:i], "://") || strings.Contains(line[:i+1], " ;") {
+			r.Marker = strings.TrimSpace(line[i+1:])
+			line = strings.TrimSpace(line[:i])
+		}
+	}
+
+	// name @ url, a PEP 508 direct reference
+	if name, uri, found := strings.Cut(line, "@"); found && strings.Contains(uri, "://") && !strings.Contains(name, "://") {
+		m := nameRegexp.FindStringSubmatch(strings.TrimSpace(name))
+		if m == nil {
+			return r, fmt.Errorf("invalid requirement %q", line)
+		}
+		r.Name = m[1]
+		r.Extras = extras(m[3])
+		r.URL = strings.TrimSpace(uri)
+		return r, nil
+	}
+
+	// plain urls, vcs urls and paths, the name is given by #egg=
+	if strings.Contains(line, "://") || strings.HasPrefix(line, ".") || strings.HasPrefix(line, "/") || strings.HasPrefix(line, "git+") {
+		r.URL = line
+		if m := eggRegexp.FindStringSubmatch(line); m != nil {
+			r.Name = m[1]
+		}
+		return r, nil
+	}
+
+	m := nameRegexp.FindStringSubmatch(line)
+	if m == nil {
+		return r, fmt.Errorf("invalid requirement %q", line)
+	}
+	r.Name = m[1]
+	r.Extras = extras(m[3])
+	r.Specifier = strings.Join(strings.Fields(strings.Trim(m[4], "()")), "")
+	return r, nil
+}
+
+func extras(s string) []string {
+	var e []string
+	for _, x := range strings.Split(s, ",") {
+		if x = strings.TrimSpace(x); x != "" {
+			e = append(e, x)
+		}
+	}
+	return e
+}
+
+var normalizeRegexp = regexp.MustCompile(`[-_.]+`)
+
+// Normalize returns the PEP 503 normalized form of a package name
+func Normalize(name string) string {
+	return strings.ToLower(normalizeRegexp.ReplaceAllString(name, "-"))
+}
@@ -0,0 +1,82 @@
+package pypi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadRequirements(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"requirements.txt": `# app requirements
+-r common/base.txt
+-c constraints.txt
+--index-url https://pypi.example.com/simple
+
+Django==4.2.7 \
+    --hash=sha256:8e0f1c2c2786b5c0e39fe1afce24c926040fad47c8ea8ad30aaf1188df29fc41 \
+    --hash=sha256:e1d37c51ad26186de355cbcec16613ebdabfa9689bbade9c538835205a8abbe9
+requests[socks, security] >= 2.28  # ranges need resolving
+urllib3 ~= 2.0
+arrow===1.3.0 ; python_version >= "3.8"
+git+https://github.com/example/fork.git@v1.2#egg=fork
+mylib @ https://files.example.com/mylib-1.0.tar.gz
+-e ./libs/local
+`,
+		"common/base.txt": `-r ../requirements.txt
+six==1.16.0
+`,
+		"constraints.txt": `urllib3==2.0.7
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reqs, err := ReadRequirements(filepath.Join(dir, "requirements.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type want struct {
+		name, version, url string
+		pinned, local      bool
+	}
+	wants := []want{
+		{name: "six", version: "1.16.0", pinned: true},
+		{name: "Django", version: "4.2.7", pinned: true},
+		{name: "requests"},
+		{name: "urllib3"},
+		{name: "arrow", version: "1.3.0", pinned: true},
+		{name: "fork", url: "git+https://github.com/example/fork.git@v1.2#egg=fork"},
+		{name: "mylib", url: "https://files.example.com/mylib-1.0.tar.gz"},
+		{url: "./libs/local", local: true},
+	}
+	if len(reqs.Requirements) != len(wants) {
+		t.Fatalf("expected %d requirements, got %d: %+v", len(wants), len(reqs.Requirements), reqs.Requirements)
+	}
+	for i, w := range wants {
+		r := reqs.Requirements[i]
+		v, pinned := r.Version()
+		if r.Name != w.name || v != w.version || pinned != w.pinned || r.URL != w.url || r.Local() != w.local {
+			t.Fatalf("expected %+v, got %+v", w, r)
+		}
+	}
+
+	if reqs.Requirements[2].Specifier != ">=2.28" || len(reqs.Requirements[2].Extras) != 2 {
+		t.Fatalf("unexpected requests requirement %+v", reqs.Requirements[2])
+	}
+	if reqs.Requirements[1].Line != 6 {
+		t.Fatalf("expected continued line to start at 6, got %d", reqs.Requirements[1].Line)
+	}
+	if v, ok := reqs.Constraint("URLLIB3"); !ok || v != "2.0.7" {
+		t.Fatalf("expected urllib3 constrained to 2.0.7, got %s", v)
+	}
+}
@@ -0,0 +1,79 @@
+package pypi
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The version pattern of PEP 440, ref. https://peps.python.org/pep-0440/#appendix-b-parsing-version-strings-with-regular-expressions
+var versionRegexp = regexp.MustCompile(`^\s*v?` +
+	`(?:(?P<epoch>[0-9]+)!)?` +
+	`(?P<release>[0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_.]?(?P<pre_l>alpha|a|beta|b|preview|pre|c|rc)[-_.]?(?P<pre_n>[0-9]+)?)?` +
+	`(?:-(?P<post_n1>[0-9]+)|[-_.]?(?P<post_l>post|rev|r)[-_.]?(?P<post_n2>[0-9]+)?)?` +
+	`(?:[-_.]?(?P<dev_l>dev)[-_.]?(?P<dev_n>[0-9]+)?)?` +
+	`(?:\+(?P<local>[a-z0-9]+(?:[-_.][a-z0-9]+)*))?` +
+	`\s*$`)
+
+var preReleaseSpelling = map[string]string{
+	"alpha":   "a",
+	"a":       "a",
+	"beta":    "b",
+	"b":       "b",
+	"c":       "rc",
+	"pre":     "rc",
+	"preview": "rc",
+	"rc":      "rc",
+}
+
+// NormalizeVersion returns the PEP 440 normalized form of a version, e.g. 1.0.0-RC1 becomes 1.0.0rc1.
+// Versions that are not valid PEP 440 are returned as they are.
+func NormalizeVersion(version string) string {
+	m := versionRegexp.FindStringSubmatch(strings.ToLower(version))
+	if m == nil {
+		return version
+	}
+	group := func(name string) string {
+		return m[versionRegexp.SubexpIndex(name)]
+	}
+	number := func(s string) string {
+		if s == "" {
+			return "0"
+		}
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return s
+		}
+		return strconv.FormatUint(n, 10)
+	}
+
+	var b strings.Builder
+	if e := group("epoch"); e != "" && number(e) != "0" {
+		b.WriteString(number(e) + "!")
+	}
+
+	release := strings.Split(group("release"), ".")
+	for i, r := range release {
+		release[i] = number(r)
+	}
+	b.WriteString(strings.Join(release, "."))
+
+	if l := group("pre_l"); l != "" {
+		b.WriteString(preReleaseSpelling[l] + number(group("pre_n")))
+	}
+	if n := group("post_n1"); n != "" {
+		b.WriteString(".post" + number(n))
+	} else if group("post_l") != "" {
+		b.WriteString(".post" + number(group("post_n2")))
+	}
+	if group("dev_l") != "" {
+		b.WriteString(".dev" + number(group("dev_n")))
+	}
+	if l := group("local"); l != "" {
+		b.WriteString("+" + normalizeLocal.ReplaceAllString(l, "."))
+	}
+	return b.String()
+}
+
+var normalizeLocal = regexp.MustCompile(`[-_.]`)
@@ -0,0 +1,41 @@
+package pypi
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Django":           "django",
+		"python_dateutil":  "python-dateutil",
+		"Zope.Interface":   "zope-interface",
+		"ruamel.yaml.clib": "ruamel-yaml-clib",
+		"a__-.b":           "a-b",
+	}
+	for name, want := range tests {
+		if got := Normalize(name); got != want {
+			t.Errorf("Normalize(%s) = %s, expected %s", name, got, want)
+		}
+	}
+}
+
+func TestNormalizeVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.0.0":          "1.0.0",
+		"v1.0":           "1.0",
+		"1.0.0-RC1":      "1.0.0rc1",
+		"1.0alpha":       "1.0a0",
+		"1.0.0.preview2": "1.0.0rc2",
+		"1.01.002":       "1.1.2",
+		"1.0-1":          "1.0.post1",
+		"1.0.0-post":     "1.0.0.post0",
+		"1.0.dev_3":      "1.0.dev3",
+		"0!1.0":          "1.0",
+		"2!1.0":          "2!1.0",
+		"1.0+Ubuntu-1":   "1.0+ubuntu.1",
+		"not a version":  "not a version",
+	}
+	for version, want := range tests {
+		if got := NormalizeVersion(version); got != want {
+			t.Errorf("NormalizeVersion(%s) = %s, expected %s", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package rootfs
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/modfin/depot/internal/depsdev"
+	"io/fs"
+	"strings"
+)
+
+const apkInstalled = "lib/apk/db/installed"
+
+// apkWorld lists the packages installed explicitly
+const apkWorld = "etc/apk/world"
+
+// Apk reads the alpine packages installed, from the apk database where every package is a paragraph of
+// single letter fields, P: name, V: version and L: license among them
+// ref. https://wiki.alpinelinux.org/wiki/Apk_spec
+func Apk(fsys fs.FS) ([]Package, error) {
+	b, err := fs.ReadFile(fsys, apkInstalled)
+	if err != nil {
+		return nil, err
+	}
+
+	var world map[string]bool
+	if w, err := fs.ReadFile(fsys, apkWorld); err == nil {
+		world = map[string]bool{}
+		for _, entry := range strings.Fields(string(w)) {
+			// entries may carry version constraints and repository tags, e.g. curl>=8.0 or foo@edge
+			name := strings.FieldsFunc(entry, func(r rune) bool {
+				return strings.ContainsRune("<>=~@", r)
+			})
+			if len(name) > 0 {
+				world[name[0]] = true
+			}
+		}
+	}
+
+	var pkgs []Package
+	var p Package
+	flush := func() {
+		if p.Name != "" {
+			p.Type = depsdev.APK
+			p.Auto = world != nil && !world[p.Name]
+			pkgs = append(pkgs, p)
+		}
+		p = Package{}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			flush()
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		switch key {
+		case "P":
+			p.Name = value
+		case "V":
+			p.Version = value
+		case "L":
+			if value != "" {
+				p.Licenses = []string{value}
+			}
+		}
+	}
+	flush()
+	return pkgs, scanner.Err()
+}
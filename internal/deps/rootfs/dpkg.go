@@ -0,0 +1,231 @@
+package rootfs
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/spdx"
+	"github.com/modfin/henry/slicez"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const dpkgStatus = "var/lib/dpkg/status"
+
+// distroless images keep a status file per package instead
+const dpkgStatusDir = "var/lib/dpkg/status.d"
+
+const aptExtendedStates = "var/lib/apt/extended_states"
+
+// Dpkg reads the debian packages installed, from the dpkg status database, with licenses from the
+// copyright files in /usr/share/doc
+func Dpkg(fsys fs.FS) ([]Package, error) {
+	var paragraphs []map[string]string
+
+	b, err := fs.ReadFile(fsys, dpkgStatus)
+	if err == nil {
+		paragraphs = append(paragraphs, controlParagraphs(b)...)
+	}
+	entries, dirErr := fs.ReadDir(fsys, dpkgStatusDir)
+	if err != nil && dirErr != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || strings.HasSuffix(e.Name(), ".md5sums") {
+			continue
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dpkgStatusDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		paragraphs = append(paragraphs, controlParagraphs(b)...)
+	}
+
+	auto := map[string]bool{}
+	if b, err := fs.ReadFile(fsys, aptExtendedStates); err == nil {
+		for _, p := range controlParagraphs(b) {
+			auto[p["Package"]] = p["Auto-Installed"] == "1"
+		}
+	}
+
+	var pkgs []Package
+	for _, p := range paragraphs {
+		// status.d files of distroless lack the status field, they are installed by definition
+		if status, ok := p["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+		name := p["Package"]
+		if name == "" {
+			continue
+		}
+		pkgs = append(pkgs, Package{
+			Type:     depsdev.DEB,
+			Name:     name,
+			Version:  p["Version"],
+			Licenses: debLicenses(fsys, name),
+			Auto:     auto[name],
+		})
+	}
+	return pkgs, nil
+}
+
+// controlParagraphs reads the debian control file format, paragraphs of Key: value fields separated by
+// blank lines, where lines starting with whitespace continue the previous field
+func controlParagraphs(b []byte) []map[string]string {
+	var paragraphs []map[string]string
+	fields := map[string]string{}
+	var last string
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(fields) > 0 {
+				paragraphs = append(paragraphs, fields)
+			}
+			fields = map[string]string{}
+			continue
+		}
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if last != "" {
+				fields[last] += "\n" + strings.TrimSpace(line)
+			}
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		last = key
+		fields[key] = strings.TrimSpace(value)
+	}
+	if len(fields) > 0 {
+		paragraphs = append(paragraphs, fields)
+	}
+	return paragraphs
+}
+
+// Short license names of the debian copyright format that differ from their SPDX identifier
+// ref. https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/#license-specification
+var debianLicenses = map[string]string{
+	"expat":         "MIT",
+	"gpl-1":         "GPL-1.0-only",
+	"gpl-1+":        "GPL-1.0-or-later",
+	"gpl-2":         "GPL-2.0-only",
+	"gpl-2+":        "GPL-2.0-or-later",
+	"gpl-3":         "GPL-3.0-only",
+	"gpl-3+":        "GPL-3.0-or-later",
+	"lgpl-2":        "LGPL-2.0-only",
+	"lgpl-2+":       "LGPL-2.0-or-later",
+	"lgpl-2.1":      "LGPL-2.1-only",
+	"lgpl-2.1+":     "LGPL-2.1-or-later",
+	"lgpl-3":        "LGPL-3.0-only",
+	"lgpl-3+":       "LGPL-3.0-or-later",
+	"agpl-3":        "AGPL-3.0-only",
+	"agpl-3+":       "AGPL-3.0-or-later",
+	"gfdl-1.2+":     "GFDL-1.2-or-later",
+	"gfdl-1.3+":     "GFDL-1.3-or-later",
+	"apache-2.0":    "Apache-2.0",
+	"bsd-2-clause":  "BSD-2-Clause",
+	"bsd-3-clause":  "BSD-3-Clause",
+	"bsd-4-clause":  "BSD-4-Clause",
+	"isc":           "ISC",
+	"zlib":          "Zlib",
+	"mpl-2.0":       "MPL-2.0",
+	"public-domain": "LicenseRef-Public-Domain",
+	"artistic":      "Artistic-1.0-Perl",
+	"perl":          "Artistic-1.0-Perl OR GPL-1.0-or-later",
+	"cc0-1.0":       "CC0-1.0",
+	"openssl":       "OpenSSL",
+}
+
+// debLicense maps a debian short name to its SPDX identifier, names not recognised are ~non-standard
+func debLicense(name string) string {
+	if id, ok := debianLicenses[strings.ToLower(name)]; ok {
+		return id
+	}
+	if id, ok := spdx.FromName(name); ok {
+		return id
+	}
+	return "~non-standard"
+}
+
+// debExpression maps the short names of a License field to an SPDX expression. "and" binds tighter than "or",
+// unless preceded by a comma, e.g. "GPL-2+ or Artistic, and BSD-3-clause". Expressions with names not
+// recognised are ~non-standard.
+// ref. https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/#license-syntax
+func debExpression(l string) string {
+	var groups []string
+	for _, group := range strings.Split(l, ", and ") {
+		var alts []string
+		for _, alt := range strings.Split(group, " or ") {
+			var parts []string
+			for _, part := range strings.Split(alt, " and ") {
+				part = strings.TrimSuffix(strings.TrimSpace(part), ",")
+				if part == "" {
+					continue
+				}
+				id := debLicense(part)
+				if id == "~non-standard" {
+					return id
+				}
+				parts = append(parts, id)
+			}
+			if len(parts) > 0 {
+				alts = append(alts, spdxJoin(parts, " AND "))
+			}
+		}
+		if len(alts) > 0 {
+			groups = append(groups, spdxJoin(alts, " OR "))
+		}
+	}
+	return spdxJoin(groups, " AND ")
+}
+
+// spdxJoin joins SPDX expressions with AND or OR, parenthesising those with OR joined with AND
+func spdxJoin(exprs []string, op string) string {
+	if op == " AND " && len(exprs) > 1 {
+		exprs = slicez.Map(exprs, func(e string) string {
+			if strings.Contains(e, " OR ") {
+				return "(" + e + ")"
+			}
+			return e
+		})
+	}
+	return strings.Join(exprs, op)
+}
+
+// debLicenses reads /usr/share/doc/<package>/copyright. Machine readable copyright files list the licenses
+// in License fields, for others the text is matched against the licenses we recognise.
+func debLicenses(fsys fs.FS, name string) []string {
+	b, err := fs.ReadFile(fsys, path.Join("usr/share/doc", name, "copyright"))
+	if err != nil {
+		return nil
+	}
+
+	var licenses []string
+	paragraphs := controlParagraphs(b)
+	if len(paragraphs) > 0 && strings.Contains(paragraphs[0]["Format"], "copyright-format") {
+		for _, p := range paragraphs {
+			l, ok := p["License"]
+			if !ok {
+				continue
+			}
+			// the first line is the short name, the text may follow
+			l, _, _ = strings.Cut(l, "\n")
+			if expression := debExpression(l); expression != "" {
+				licenses = append(licenses, expression)
+			}
+		}
+	}
+	if len(licenses) > 0 {
+		return slicez.Uniq(licenses)
+	}
+
+	if id, ok := spdx.FromText(string(b)); ok {
+		return []string{id}
+	}
+	return nil
+}
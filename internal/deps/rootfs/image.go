@@ -0,0 +1,158 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/modfin/henry/mapz"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"testing/fstest"
+)
+
+// IsImage tells if a file name may be an image tarball as written by docker save
+func IsImage(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), ".tar")
+}
+
+// databaseFile matches the files of the root filesystem we read, the package databases and debian copyright files
+var databaseFile = regexp.MustCompile(`^(var/lib/dpkg/status|var/lib/dpkg/status\.d/[^/]+|var/lib/apt/extended_states|usr/share/doc/[^/]+/copyright|lib/apk/db/installed|etc/apk/world|(var/lib|usr/lib/sysimage)/rpm/(rpmdb\.sqlite|Packages|Packages\.db))$`)
+
+type layer struct {
+	files map[string][]byte
+	// removed are the paths whited out by the layer, opaque the directories it replaces entirely
+	removed []string
+	opaque  []string
+}
+
+// Image reads the package databases of an image saved with docker save, applying its layers in order
+func Image(path string) (fs.FS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var manifest []struct {
+		Layers []string `json:"Layers"`
+	}
+	layers := map[string]layer{}
+
+	// the manifest may come after the layers it lists, so all layers are read before applying them
+	tr := tar.NewReader(f)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(h.Name, "./")
+		if name == "manifest.json" {
+			if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+				return nil, fmt.Errorf("could not read manifest.json of %s: %w", path, err)
+			}
+			continue
+		}
+		l, ok, err := readLayer(tr)
+		if err != nil {
+			return nil, fmt.Errorf("could not read layer %s of %s: %w", name, path, err)
+		}
+		if ok {
+			layers[name] = l
+		}
+	}
+	if len(manifest) == 0 {
+		return nil, fmt.Errorf("%s has no manifest.json, is it written by docker save?", path)
+	}
+	if len(manifest) > 1 {
+		return nil, fmt.Errorf("%s holds %d images, save one image at a time", path, len(manifest))
+	}
+
+	files := map[string][]byte{}
+	for _, name := range manifest[0].Layers {
+		l, ok := layers[strings.TrimPrefix(name, "./")]
+		if !ok {
+			return nil, fmt.Errorf("layer %s of %s is missing", name, path)
+		}
+		for _, p := range mapz.Keys(files) {
+			for _, dir := range l.opaque {
+				if strings.HasPrefix(p, dir+"/") {
+					delete(files, p)
+				}
+			}
+			for _, r := range l.removed {
+				if p == r || strings.HasPrefix(p, r+"/") {
+					delete(files, p)
+				}
+			}
+		}
+		for p, b := range l.files {
+			files[p] = b
+		}
+	}
+
+	fsys := fstest.MapFS{}
+	for p, b := range files {
+		fsys[p] = &fstest.MapFile{Data: b, Mode: 0644}
+	}
+	return fsys, nil
+}
+
+// readLayer reads the package databases from a layer tarball, which may be gzip compressed.
+// Entries that are not tarballs, such as the image config, are skipped.
+func readLayer(r io.Reader) (layer, bool, error) {
+	l := layer{files: map[string][]byte{}}
+
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return l, false, err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+	// tar archives have ustar at offset 257 of the first header
+	if magic, err := br.Peek(262); err != nil || !strings.HasPrefix(string(magic[257:]), "ustar") {
+		return l, false, nil
+	}
+
+	tr := tar.NewReader(br)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return l, true, nil
+		}
+		if err != nil {
+			return l, false, err
+		}
+		name := path.Clean(strings.TrimPrefix(h.Name, "/"))
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+
+		switch {
+		case base == ".wh..wh..opq":
+			l.opaque = append(l.opaque, dir)
+		case strings.HasPrefix(base, ".wh."):
+			l.removed = append(l.removed, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+		case h.Typeflag == tar.TypeReg && databaseFile.MatchString(name):
+			b, err := io.ReadAll(tr)
+			if err != nil {
+				return l, false, err
+			}
+			l.files[name] = b
+		}
+	}
+}
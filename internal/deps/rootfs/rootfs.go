@@ -0,0 +1,49 @@
+package rootfs
+
+import (
+	"errors"
+	"github.com/modfin/depot/internal/depsdev"
+	"io/fs"
+)
+
+// Package is an operating system package installed in a root filesystem
+type Package struct {
+	Type     depsdev.DepType
+	Name     string
+	Version  string
+	Licenses []string
+	// Auto is true for packages installed as a dependency of another, as recorded by apt and apk
+	Auto bool
+}
+
+// IsRootFS tells if a directory holds a package database of dpkg, apk or rpm
+func IsRootFS(fsys fs.FS) bool {
+	for _, db := range append([]string{dpkgStatus, apkInstalled}, rpmDatabases...) {
+		if _, err := fs.Stat(fsys, db); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Packages reads the packages installed in a root filesystem from the package databases found in it
+func Packages(fsys fs.FS) ([]Package, error) {
+	var pkgs []Package
+	var found bool
+
+	for _, read := range []func(fs.FS) ([]Package, error){Dpkg, Apk, Rpm} {
+		p, err := read(fsys)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		pkgs = append(pkgs, p...)
+	}
+	if !found {
+		return nil, errors.New("no dpkg, apk or rpm package database found")
+	}
+	return pkgs, nil
+}
@@ -0,0 +1,376 @@
+package rootfs
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/henry/mapz"
+	"github.com/modfin/henry/slicez"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func describe(pkgs []Package) []string {
+	return slicez.Map(pkgs, func(p Package) string {
+		s := fmt.Sprintf("%s %s %s %s", p.Type, p.Name, p.Version, strings.Join(p.Licenses, ","))
+		if p.Auto {
+			s += " auto"
+		}
+		return s
+	})
+}
+
+func check(t *testing.T, got []Package, want []string) {
+	t.Helper()
+	if !slicez.Equal(describe(got), want) {
+		t.Fatalf("expected %v, got %v", want, describe(got))
+	}
+}
+
+const dpkgStatusFile = `Package: libc6
+Status: install ok installed
+Priority: optional
+Architecture: amd64
+Multi-Arch: same
+Version: 2.36-9+deb12u3
+Description: GNU C Library: Shared libraries
+ Contains the standard libraries that are used by nearly all programs on
+ the system.
+
+Package: tzdata
+Status: install ok installed
+Architecture: all
+Version: 2024a-0+deb12u1
+
+Package: curl
+Status: deinstall ok config-files
+Architecture: amd64
+Version: 7.88.1-10+deb12u5
+`
+
+const libc6Copyright = `Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/
+Upstream-Name: glibc
+
+Files: *
+Copyright: 1991-2023 Free Software Foundation, Inc.
+License: LGPL-2.1+
+ This library is free software; you can redistribute it and/or
+ .
+ modify it under the terms of the GNU Lesser General Public
+
+Files: debian/*
+Copyright: 1998-2023 the debian glibc maintainers
+License: GPL-2+ or LGPL-2.1+, and Expat
+
+Files: debian/vendor/*
+Copyright: 2023 a vendor
+License: Vendor-EULA
+`
+
+func TestDpkg(t *testing.T) {
+	fsys := fstest.MapFS{
+		"var/lib/dpkg/status":            {Data: []byte(dpkgStatusFile)},
+		"usr/share/doc/libc6/copyright":  {Data: []byte(libc6Copyright)},
+		"usr/share/doc/tzdata/copyright": {Data: []byte("This is the Debian prepackaged version of the Time Zone and Daylight\nSaving Time Database. It is in the public domain.\n")},
+		"var/lib/apt/extended_states":    {Data: []byte("Package: tzdata\nArchitecture: all\nAuto-Installed: 1\n")},
+	}
+	pkgs, err := Dpkg(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, pkgs, []string{
+		"deb libc6 2.36-9+deb12u3 LGPL-2.1-or-later,(GPL-2.0-or-later OR LGPL-2.1-or-later) AND MIT,~non-standard",
+		"deb tzdata 2024a-0+deb12u1  auto",
+	})
+
+	for l, want := range map[string]string{
+		"GPL-2+ or Artistic":                  "GPL-2.0-or-later OR Artistic-1.0-Perl",
+		"GPL-2+ and BSD-3-clause or Expat":    "GPL-2.0-or-later AND BSD-3-Clause OR MIT",
+		"Perl and Expat":                      "(Artistic-1.0-Perl OR GPL-1.0-or-later) AND MIT",
+		"GPL-2+ or Artistic, and Vendor-EULA": "~non-standard",
+	} {
+		if got := debExpression(l); got != want {
+			t.Fatalf("%s: expected %s, got %s", l, want, got)
+		}
+	}
+}
+
+func TestDpkgDistroless(t *testing.T) {
+	fsys := fstest.MapFS{
+		"var/lib/dpkg/status.d/base-files":         {Data: []byte("Package: base-files\nVersion: 12.4+deb12u5\nArchitecture: amd64\n")},
+		"var/lib/dpkg/status.d/base-files.md5sums": {Data: []byte("e0a1d5e1b0b4a5ad0fc7e7f0e6c1a0f4  etc/debian_version\n")},
+	}
+	pkgs, err := Dpkg(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, pkgs, []string{"deb base-files 12.4+deb12u5 "})
+}
+
+func TestApk(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lib/apk/db/installed": {Data: []byte(`C:Q1pNmR5c4p3hDh9hHt/+UvAcJSaUo=
+P:musl
+V:1.2.4-r2
+A:x86_64
+L:MIT
+o:musl
+
+C:Q1Vk4S5pC3bA3q3qG3fRmG9rqMg1E=
+P:ca-certificates-bundle
+V:20230506-r0
+L:MPL-2.0 AND MIT
+
+P:busybox
+V:1.36.1-r5
+L:GPL-2.0-only
+`)},
+		"etc/apk/world": {Data: []byte("busybox\nmusl>=1.2\n")},
+	}
+	pkgs, err := Apk(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, pkgs, []string{
+		"apk musl 1.2.4-r2 MIT",
+		"apk ca-certificates-bundle 20230506-r0 MPL-2.0 AND MIT auto",
+		"apk busybox 1.36.1-r5 GPL-2.0-only",
+	})
+}
+
+var rpmWant = []string{
+	"rpm bash 5.1.8-6.el9 GPLv3+",
+	"rpm openssl-libs 1:3.0.7-24.el9 ASL 2.0",
+}
+
+func TestRpmSqlite(t *testing.T) {
+	b, err := os.ReadFile("./testdata/rpmdb.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkgs, err := Rpm(fstest.MapFS{"var/lib/rpm/rpmdb.sqlite": {Data: b}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the fixture has filler packages to span interior pages, bash and openssl-libs overflow theirs
+	if len(pkgs) != 12 {
+		t.Fatalf("expected 12 packages, got %v", describe(pkgs))
+	}
+	check(t, pkgs[:2], rpmWant)
+}
+
+// rpmHeaders are the headers of bash and openssl-libs, as found in the sqlite fixture
+func rpmHeaders(t *testing.T) [][]byte {
+	b, err := os.ReadFile("./testdata/rpmdb.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blobs, err := sqliteBlobs(b, "Packages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blobs[1:3]
+}
+
+func TestSqliteCorrupt(t *testing.T) {
+	b, err := os.ReadFile("./testdata/rpmdb.sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// truncated and corrupt databases fail rather than panic
+	for i := range b {
+		_, _ = sqliteBlobs(b[:i], "Packages")
+
+		corrupt := append([]byte{}, b...)
+		corrupt[i] ^= 0xff
+		_, _ = sqliteBlobs(corrupt, "Packages")
+	}
+
+	// an interior page pointing back at itself
+	pageSize := int(binary.BigEndian.Uint16(b[16:18]))
+	for n := 2; n*pageSize <= len(b); n++ {
+		at := (n - 1) * pageSize
+		if b[at] != 0x05 {
+			continue
+		}
+		cyclic := append([]byte{}, b...)
+		binary.BigEndian.PutUint32(cyclic[at+8:at+12], uint32(n))
+		if _, err := sqliteBlobs(cyclic, "Packages"); err == nil || !strings.Contains(err.Error(), "revisits") {
+			t.Fatalf("expected the cycle at page %d to fail, got %v", n, err)
+		}
+		return
+	}
+	t.Fatal("expected the fixture to have an interior page")
+}
+
+func TestRpmNdb(t *testing.T) {
+	le := binary.LittleEndian
+	db := make([]byte, 4096)
+	copy(db, "RpmP")
+	le.PutUint32(db[12:], 1)
+
+	for i, h := range rpmHeaders(t) {
+		slot := db[32+16*i:]
+		copy(slot, "Slot")
+		le.PutUint32(slot[4:], uint32(i+1))
+		le.PutUint32(slot[8:], uint32(len(db)/16))
+
+		blob := make([]byte, 16+len(h))
+		copy(blob, "BlbS")
+		le.PutUint32(blob[4:], uint32(i+1))
+		le.PutUint32(blob[12:], uint32(len(h)))
+		copy(blob[16:], h)
+		for len(blob)%16 != 0 {
+			blob = append(blob, 0)
+		}
+		db = append(db, blob...)
+	}
+
+	pkgs, err := Rpm(fstest.MapFS{"usr/lib/sysimage/rpm/Packages.db": {Data: db}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, pkgs, rpmWant)
+}
+
+func TestRpmBdb(t *testing.T) {
+	const pageSize = 2048
+	le := binary.LittleEndian
+	headers := rpmHeaders(t)
+
+	page := func(n int, kind byte) []byte {
+		p := make([]byte, pageSize)
+		le.PutUint32(p[8:], uint32(n))
+		p[25] = kind
+		return p
+	}
+	meta := page(0, 8)
+	le.PutUint32(meta[12:], 0x061561)
+	le.PutUint32(meta[20:], pageSize)
+
+	// page 1 holds the small header inline and refers to the large one in overflow pages from page 2
+	hash := page(1, 13)
+	le.PutUint16(hash[20:], 4)
+	key := []byte{1, 1, 0, 0, 0}
+	inline := append([]byte{1}, headers[0]...)
+	if len(inline) > pageSize/2 {
+		t.Fatalf("the bash header is expected to fit a page, it is %d bytes", len(inline))
+	}
+	items := [][]byte{key, inline, key, nil}
+	offpage := make([]byte, 12)
+	offpage[0] = 3
+	le.PutUint32(offpage[4:], 2)
+	le.PutUint32(offpage[8:], uint32(len(headers[1])))
+	items[3] = offpage
+
+	end := pageSize
+	for i, item := range items {
+		end -= len(item)
+		copy(hash[end:], item)
+		le.PutUint16(hash[26+2*i:], uint16(end))
+	}
+
+	db := append(meta, hash...)
+	rest := headers[1]
+	for n := 2; len(rest) > 0; n++ {
+		p := page(n, 7)
+		chunk := min(len(rest), pageSize-26)
+		copy(p[26:], rest[:chunk])
+		le.PutUint16(p[22:], uint16(chunk))
+		rest = rest[chunk:]
+		if len(rest) > 0 {
+			le.PutUint32(p[16:], uint32(n+1))
+		}
+		db = append(db, p...)
+	}
+
+	pkgs, err := Rpm(fstest.MapFS{"var/lib/rpm/Packages": {Data: db}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, pkgs, rpmWant)
+
+	// an overflow page linked to itself, or adding nothing, fails rather than loops
+	if len(headers[1]) <= pageSize-26 {
+		t.Fatalf("the large header is expected to overflow a page, it is %d bytes", len(headers[1]))
+	}
+	cyclic := append([]byte{}, db...)
+	le.PutUint32(cyclic[2*pageSize+16:], 2)
+	if _, err := bdbBlobs(cyclic); err == nil || !strings.Contains(err.Error(), "revisits") {
+		t.Fatalf("expected the self-linked overflow page to fail, got %v", err)
+	}
+	empty := append([]byte{}, db...)
+	le.PutUint16(empty[2*pageSize+22:], 0)
+	if _, err := bdbBlobs(empty); err == nil {
+		t.Fatal("expected the empty overflow page to fail")
+	}
+}
+
+func tarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range slicez.Sort(mapz.Keys(files)) {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg, Format: tar.FormatUSTAR})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImage(t *testing.T) {
+	base := tarball(t, map[string]string{
+		"var/lib/dpkg/status":           dpkgStatusFile,
+		"usr/share/doc/libc6/copyright": libc6Copyright,
+		"etc/os-release":                "ID=debian\n",
+	})
+	// the second layer removes tzdata's copyright and replaces the status database
+	top := tarball(t, map[string]string{
+		"usr/share/doc/.wh.tzdata": "",
+		"var/lib/dpkg/status":      strings.Replace(dpkgStatusFile, "Version: 2024a-0+deb12u1", "Version: 2024b-0+deb12u1", 1),
+	})
+	manifest, _ := json.Marshal([]map[string]any{{
+		"Config":   "config.json",
+		"RepoTags": []string{"app:latest"},
+		"Layers":   []string{"base/layer.tar", "top/layer.tar"},
+	}})
+
+	path := filepath.Join(t.TempDir(), "app.tar")
+	err := os.WriteFile(path, tarball(t, map[string]string{
+		"top/layer.tar":  string(top),
+		"base/layer.tar": string(base),
+		"config.json":    `{"architecture":"amd64"}`,
+		"manifest.json":  string(manifest),
+	}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, err := Image(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsRootFS(fsys) {
+		t.Fatal("expected the image to hold a package database")
+	}
+	pkgs, err := Packages(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, pkgs, []string{
+		"deb libc6 2.36-9+deb12u3 LGPL-2.1-or-later,(GPL-2.0-or-later OR LGPL-2.1-or-later) AND MIT,~non-standard",
+		"deb tzdata 2024b-0+deb12u1 ",
+	})
+}
@@ -0,0 +1,280 @@
+package rootfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"io/fs"
+	"path"
+	"strconv"
+)
+
+// rpmDatabases are the package databases of rpm, sqlite since rpm 4.16, ndb on suse and berkeley db before,
+// kept in /usr/lib/sysimage/rpm on newer systems with /var/lib/rpm linking there
+var rpmDatabases = []string{
+	"usr/lib/sysimage/rpm/rpmdb.sqlite",
+	"var/lib/rpm/rpmdb.sqlite",
+	"usr/lib/sysimage/rpm/Packages.db",
+	"var/lib/rpm/Packages.db",
+	"usr/lib/sysimage/rpm/Packages",
+	"var/lib/rpm/Packages",
+}
+
+// Rpm reads the rpm packages installed, from the headers stored in the rpm database
+func Rpm(fsys fs.FS) ([]Package, error) {
+	for _, db := range rpmDatabases {
+		b, err := fs.ReadFile(fsys, db)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var blobs [][]byte
+		switch path.Base(db) {
+		case "rpmdb.sqlite":
+			blobs, err = sqliteBlobs(b, "Packages")
+		case "Packages.db":
+			blobs, err = ndbBlobs(b)
+		default:
+			blobs, err = bdbBlobs(b)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", db, err)
+		}
+
+		var pkgs []Package
+		for _, blob := range blobs {
+			h, err := parseRpmHeader(blob)
+			if err != nil {
+				return nil, fmt.Errorf("could not read %s: %w", db, err)
+			}
+			// the public keys rpm trusts are stored as packages
+			if h.name == "gpg-pubkey" || h.name == "" {
+				continue
+			}
+			p := Package{
+				Type:    depsdev.RPM,
+				Name:    h.name,
+				Version: h.version + "-" + h.release,
+			}
+			if h.epoch != 0 {
+				p.Version = strconv.Itoa(h.epoch) + ":" + p.Version
+			}
+			if h.license != "" {
+				p.Licenses = []string{h.license}
+			}
+			pkgs = append(pkgs, p)
+		}
+		return pkgs, nil
+	}
+	return nil, fs.ErrNotExist
+}
+
+const (
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagRelease = 1002
+	rpmTagEpoch   = 1003
+	rpmTagLicense = 1014
+
+	rpmTypeInt32       = 4
+	rpmTypeString      = 6
+	rpmTypeI18NString  = 9
+	rpmHeaderIndexSize = 16
+)
+
+type rpmHeader struct {
+	name, version, release, license string
+	epoch                           int
+}
+
+// parseRpmHeader reads a header as stored in the database, an index of tags into a data store
+// ref. https://rpm-software-management.github.io/rpm/manual/format_header.html
+func parseRpmHeader(b []byte) (rpmHeader, error) {
+	var h rpmHeader
+	if len(b) < 8 {
+		return h, errors.New("rpm header too short")
+	}
+	il := int(binary.BigEndian.Uint32(b[0:4]))
+	dl := int(binary.BigEndian.Uint32(b[4:8]))
+	start := 8 + il*rpmHeaderIndexSize
+	if il < 0 || dl < 0 || start+dl > len(b) {
+		return h, errors.New("rpm header out of bounds")
+	}
+	store := b[start : start+dl]
+
+	for i := 0; i < il; i++ {
+		entry := b[8+i*rpmHeaderIndexSize:]
+		tag := binary.BigEndian.Uint32(entry[0:4])
+		typ := binary.BigEndian.Uint32(entry[4:8])
+		offset := int(binary.BigEndian.Uint32(entry[8:12]))
+		if offset < 0 || offset >= len(store) {
+			continue
+		}
+
+		str := func() string {
+			if typ != rpmTypeString && typ != rpmTypeI18NString {
+				return ""
+			}
+			s := store[offset:]
+			if end := bytes.IndexByte(s, 0); end >= 0 {
+				s = s[:end]
+			}
+			return string(s)
+		}
+		switch tag {
+		case rpmTagName:
+			h.name = str()
+		case rpmTagVersion:
+			h.version = str()
+		case rpmTagRelease:
+			h.release = str()
+		case rpmTagLicense:
+			h.license = str()
+		case rpmTagEpoch:
+			if typ == rpmTypeInt32 && offset+4 <= len(store) {
+				h.epoch = int(binary.BigEndian.Uint32(store[offset:]))
+			}
+		}
+	}
+	return h, nil
+}
+
+// ndbBlobs reads the headers of an ndb Packages.db, slots pointing at blobs in 16 byte blocks
+// ref. https://github.com/rpm-software-management/rpm/blob/master/lib/backend/ndb/rpmpkg.c
+func ndbBlobs(b []byte) ([][]byte, error) {
+	const headerMagic = 'R' | 'p'<<8 | 'm'<<16 | 'P'<<24
+	const slotMagic = 'S' | 'l'<<8 | 'o'<<16 | 't'<<24
+	const blobMagic = 'B' | 'l'<<8 | 'b'<<16 | 'S'<<24
+	const pageSize, slotSize, blockSize, blobHeaderSize = 4096, 16, 16, 16
+
+	le := binary.LittleEndian
+	if len(b) < slotSize || le.Uint32(b[0:4]) != headerMagic {
+		return nil, errors.New("not an ndb database")
+	}
+	slotPages := int(le.Uint32(b[12:16]))
+	end := min(slotPages*pageSize, len(b))
+
+	var blobs [][]byte
+	// the first two slot sized entries are the database header
+	for off := 2 * slotSize; off+slotSize <= end; off += slotSize {
+		slot := b[off : off+slotSize]
+		if le.Uint32(slot[0:4]) != slotMagic || le.Uint32(slot[4:8]) == 0 {
+			continue
+		}
+		blob := int(le.Uint32(slot[8:12])) * blockSize
+		if blob+blobHeaderSize > len(b) || le.Uint32(b[blob:blob+4]) != blobMagic {
+			return nil, errors.New("ndb slot points outside of the database")
+		}
+		length := int(le.Uint32(b[blob+12 : blob+16]))
+		if blob+blobHeaderSize+length > len(b) {
+			return nil, errors.New("ndb blob out of bounds")
+		}
+		blobs = append(blobs, b[blob+blobHeaderSize:blob+blobHeaderSize+length])
+	}
+	return blobs, nil
+}
+
+// bdbBlobs reads the values of a berkeley db hash database, where rpm keys headers by package number.
+// Values too large for a page, most headers, are kept in chains of overflow pages.
+func bdbBlobs(b []byte) ([][]byte, error) {
+	const hashMagic = 0x061561
+	const pageHeaderSize = 26
+	const (
+		pageHash         = 13
+		pageHashUnsorted = 2
+		pageOverflow     = 7
+		itemKeyData      = 1
+		itemOffPage      = 3
+	)
+
+	if len(b) < 512 {
+		return nil, errors.New("not a berkeley db database")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(b[12:16]) != hashMagic {
+		order = binary.BigEndian
+		if order.Uint32(b[12:16]) != hashMagic {
+			return nil, errors.New("not a berkeley db hash database")
+		}
+	}
+	pageSize := int(order.Uint32(b[20:24]))
+	if pageSize < 512 {
+		return nil, errors.New("invalid berkeley db page size")
+	}
+	page := func(n int) []byte {
+		if (n+1)*pageSize > len(b) {
+			return nil
+		}
+		return b[n*pageSize : (n+1)*pageSize]
+	}
+
+	// overflow reads a value chained over pages, a page visited before or one adding nothing is a corrupt database
+	overflow := func(pgno int, length int) ([]byte, error) {
+		length = min(length, len(b))
+		visited := map[int]bool{}
+		var data []byte
+		for pgno != 0 && len(data) < length {
+			if visited[pgno] {
+				return nil, errors.New("berkeley db overflow chain revisits a page")
+			}
+			visited[pgno] = true
+			p := page(pgno)
+			if p == nil || p[25] != pageOverflow {
+				return nil, errors.New("broken berkeley db overflow chain")
+			}
+			used := int(order.Uint16(p[22:24]))
+			if pageHeaderSize+used > len(p) {
+				return nil, errors.New("berkeley db overflow page out of bounds")
+			}
+			if used == 0 {
+				return nil, errors.New("empty berkeley db overflow page")
+			}
+			data = append(data, p[pageHeaderSize:pageHeaderSize+used]...)
+			pgno = int(order.Uint32(p[16:20]))
+		}
+		return data, nil
+	}
+
+	var blobs [][]byte
+	for n := 1; (n+1)*pageSize <= len(b); n++ {
+		p := page(n)
+		if p[25] != pageHash && p[25] != pageHashUnsorted {
+			continue
+		}
+		entries := int(order.Uint16(p[20:22]))
+		// entries alternate between key and value, only the values are of interest
+		for i := 1; i < entries; i += 2 {
+			at := pageHeaderSize + 2*i
+			if at+2 > len(p) {
+				break
+			}
+			item := int(order.Uint16(p[at : at+2]))
+			if item >= len(p) {
+				continue
+			}
+			switch p[item] {
+			case itemOffPage:
+				if item+12 > len(p) {
+					continue
+				}
+				data, err := overflow(int(order.Uint32(p[item+4:item+8])), int(order.Uint32(p[item+8:item+12])))
+				if err != nil {
+					return nil, err
+				}
+				blobs = append(blobs, data)
+			case itemKeyData:
+				// the value runs to the start of the previous item, items are laid out from the end of the page
+				end := int(order.Uint16(p[at-2 : at]))
+				if end > item && end <= len(p) {
+					blobs = append(blobs, p[item+1:end])
+				}
+			}
+		}
+	}
+	return blobs, nil
+}
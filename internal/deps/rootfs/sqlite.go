@@ -0,0 +1,260 @@
+package rootfs
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// sqliteBlobs reads the blob column of a table in an sqlite database, enough of the file format to read the
+// rpm database, a table of header blobs, without depending on sqlite. Changes in a write ahead log not yet
+// checkpointed into the database are not seen.
+// ref. https://www.sqlite.org/fileformat.html
+func sqliteBlobs(db []byte, table string) ([][]byte, error) {
+	if len(db) < 100 || string(db[:16]) != "SQLite format 3\x00" {
+		return nil, errors.New("not an sqlite database")
+	}
+	pageSize := int(binary.BigEndian.Uint16(db[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	usable := pageSize - int(db[20])
+	// pages are a power of two of at least 512 bytes, of which at least 480 usable
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 || usable < 480 {
+		return nil, errors.New("invalid sqlite page size")
+	}
+
+	r := sqliteReader{db: db, pageSize: pageSize, usable: usable}
+
+	// the schema table is rooted at page 1, its rows are type, name, tbl_name, rootpage and sql
+	var root int64
+	err := r.walk(1, func(record []any) {
+		if len(record) >= 4 && record[0] == "table" && record[1] == table {
+			root, _ = record[3].(int64)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if root == 0 {
+		return nil, errors.New("no table " + table)
+	}
+
+	var blobs [][]byte
+	err = r.walk(int(root), func(record []any) {
+		for _, v := range record {
+			if b, ok := v.([]byte); ok {
+				blobs = append(blobs, b)
+			}
+		}
+	})
+	return blobs, err
+}
+
+type sqliteReader struct {
+	db       []byte
+	pageSize int
+	usable   int
+}
+
+func (r sqliteReader) page(n int) ([]byte, int, error) {
+	if n < 1 || n*r.pageSize > len(r.db) {
+		return nil, 0, errors.New("sqlite page out of bounds")
+	}
+	p := r.db[(n-1)*r.pageSize : n*r.pageSize]
+	// the first page starts with the database header
+	if n == 1 {
+		return p, 100, nil
+	}
+	return p, 0, nil
+}
+
+// walk visits the rows of the table b-tree rooted at page n
+func (r sqliteReader) walk(n int, visit func(record []any)) error {
+	return r.walkPage(n, map[int]bool{}, visit)
+}
+
+// walkPage visits the rows below page n, a page visited before is a corrupt database, not a b-tree
+func (r sqliteReader) walkPage(n int, visited map[int]bool, visit func(record []any)) error {
+	if visited[n] {
+		return errors.New("sqlite b-tree revisits a page")
+	}
+	visited[n] = true
+
+	p, h, err := r.page(n)
+	if err != nil {
+		return err
+	}
+	if h+12 > len(p) {
+		return errors.New("sqlite page header out of bounds")
+	}
+	kind := p[h]
+	cells := int(binary.BigEndian.Uint16(p[h+3 : h+5]))
+
+	// cell reads the offset of cell i, from the cell pointers following the page header
+	cell := func(pointers int, i int) (int, error) {
+		at := pointers + 2*i
+		if at+2 > len(p) {
+			return 0, errors.New("sqlite cell pointer out of bounds")
+		}
+		c := int(binary.BigEndian.Uint16(p[at : at+2]))
+		if c >= len(p) {
+			return 0, errors.New("sqlite cell out of bounds")
+		}
+		return c, nil
+	}
+
+	switch kind {
+	case 0x05: // interior table page
+		for i := 0; i < cells; i++ {
+			c, err := cell(h+12, i)
+			if err != nil {
+				return err
+			}
+			if c+4 > len(p) {
+				return errors.New("sqlite cell out of bounds")
+			}
+			if err := r.walkPage(int(binary.BigEndian.Uint32(p[c:c+4])), visited, visit); err != nil {
+				return err
+			}
+		}
+		return r.walkPage(int(binary.BigEndian.Uint32(p[h+8:h+12])), visited, visit)
+
+	case 0x0d: // leaf table page
+		for i := 0; i < cells; i++ {
+			c, err := cell(h+8, i)
+			if err != nil {
+				return err
+			}
+			payload, err := r.payload(p, c)
+			if err != nil {
+				return err
+			}
+			record, err := sqliteRecord(payload)
+			if err != nil {
+				return err
+			}
+			visit(record)
+		}
+		return nil
+	}
+	return errors.New("unexpected sqlite page type")
+}
+
+// payload reads the record of a leaf cell, following overflow pages for records too large to fit the page
+func (r sqliteReader) payload(p []byte, cell int) ([]byte, error) {
+	size, n := sqliteVarint(p[cell:])
+	cell += n
+	_, n = sqliteVarint(p[cell:]) // rowid
+	cell += n
+
+	// no record is larger than the database
+	if size > uint64(len(r.db)) {
+		return nil, errors.New("sqlite record size out of bounds")
+	}
+	total := int(size)
+	maxLocal := r.usable - 35
+	if total <= maxLocal {
+		if cell+total > len(p) {
+			return nil, errors.New("sqlite cell out of bounds")
+		}
+		return p[cell : cell+total], nil
+	}
+
+	minLocal := (r.usable-12)*32/255 - 23
+	local := minLocal + (total-minLocal)%(r.usable-4)
+	if local > maxLocal {
+		local = minLocal
+	}
+	if cell+local+4 > len(p) {
+		return nil, errors.New("sqlite cell out of bounds")
+	}
+	payload := append([]byte{}, p[cell:cell+local]...)
+	next := int(binary.BigEndian.Uint32(p[cell+local:]))
+	for next != 0 && len(payload) < total {
+		o, _, err := r.page(next)
+		if err != nil {
+			return nil, err
+		}
+		chunk := min(total-len(payload), r.usable-4)
+		payload = append(payload, o[4:4+chunk]...)
+		next = int(binary.BigEndian.Uint32(o[0:4]))
+	}
+	if len(payload) < total {
+		return nil, errors.New("sqlite overflow chain too short")
+	}
+	return payload, nil
+}
+
+// sqliteRecord decodes a record, a header of serial types followed by the values, into int64, string, []byte or nil
+func sqliteRecord(b []byte) ([]any, error) {
+	headerSize, n := sqliteVarint(b)
+	if headerSize > uint64(len(b)) {
+		return nil, errors.New("sqlite record out of bounds")
+	}
+	var types []int64
+	for at := n; at < int(headerSize); {
+		t, n := sqliteVarint(b[at:])
+		types = append(types, int64(t))
+		at += n
+	}
+
+	var values []any
+	at := int(headerSize)
+	for _, t := range types {
+		var size int
+		switch {
+		case t >= 1 && t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6 || t == 7:
+			size = 8
+		case t >= 12:
+			size = int(t-12) / 2
+		}
+		if at+size > len(b) {
+			return nil, errors.New("sqlite record out of bounds")
+		}
+		v := b[at : at+size]
+		at += size
+
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t >= 1 && t <= 6:
+			var i int64
+			for _, c := range v {
+				i = i<<8 | int64(c)
+			}
+			// sign extend
+			shift := 64 - 8*len(v)
+			values = append(values, i<<shift>>shift)
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t >= 12 && t%2 == 0:
+			values = append(values, v)
+		case t >= 13:
+			values = append(values, string(v))
+		default:
+			values = append(values, nil)
+		}
+	}
+	return values, nil
+}
+
+// sqliteVarint reads a big endian variable length integer of up to 9 bytes
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9 && i < len(b); i++ {
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
@@ -0,0 +1,132 @@
+package sbom
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"github.com/modfin/depot/internal/spdx"
+	"github.com/modfin/henry/slicez"
+)
+
+// CycloneDX is the parts of a CycloneDX document, json or xml, naming components and their licenses
+// ref. https://cyclonedx.org/docs/1.5/json/
+type CycloneDX struct {
+	Metadata struct {
+		Component cdxComponent `json:"component" xml:"component"`
+	} `json:"metadata" xml:"metadata"`
+	Components   []cdxComponent `json:"components" xml:"components>component"`
+	Dependencies []struct {
+		Ref       string   `json:"ref" xml:"ref,attr"`
+		DependsOn []string `json:"dependsOn" xml:"-"`
+		// xml nests the dependencies as elements with a ref attribute
+		Dependency []struct {
+			Ref string `xml:"ref,attr"`
+		} `json:"-" xml:"dependency"`
+	} `json:"dependencies" xml:"dependencies>dependency"`
+}
+
+type cdxComponent struct {
+	Ref        string         `json:"bom-ref" xml:"bom-ref,attr"`
+	Type       string         `json:"type" xml:"type,attr"`
+	Name       string         `json:"name" xml:"name"`
+	Group      string         `json:"group" xml:"group"`
+	Version    string         `json:"version" xml:"version"`
+	PURL       string         `json:"purl" xml:"purl"`
+	Licenses   cdxLicenses    `json:"licenses" xml:"licenses"`
+	Components []cdxComponent `json:"components" xml:"components>component"`
+}
+
+// cdxLicenses is a list of licenses by id or name, or a single expression
+type cdxLicenses struct {
+	Licenses    []cdxLicense `xml:"license"`
+	Expressions []string     `xml:"expression"`
+}
+
+type cdxLicense struct {
+	ID   string `json:"id" xml:"id"`
+	Name string `json:"name" xml:"name"`
+}
+
+func (l *cdxLicenses) UnmarshalJSON(b []byte) error {
+	var choices []struct {
+		License    *cdxLicense `json:"license"`
+		Expression string      `json:"expression"`
+	}
+	if err := json.Unmarshal(b, &choices); err != nil {
+		return err
+	}
+	for _, c := range choices {
+		if c.License != nil {
+			l.Licenses = append(l.Licenses, *c.License)
+		}
+		if c.Expression != "" {
+			l.Expressions = append(l.Expressions, c.Expression)
+		}
+	}
+	return nil
+}
+
+// SPDX returns the expressions and license ids, names are mapped to ids where they are recognised and are
+// ~non-standard otherwise, e.g. the name of a commercial license
+func (l cdxLicenses) SPDX() []string {
+	ids := append([]string{}, l.Expressions...)
+	for _, license := range l.Licenses {
+		switch {
+		case license.ID != "":
+			ids = append(ids, license.ID)
+		case license.Name != "":
+			if id, ok := spdx.FromName(license.Name); ok {
+				ids = append(ids, id)
+				continue
+			}
+			ids = append(ids, "~non-standard")
+		}
+	}
+	return slicez.Uniq(ids)
+}
+
+func cycloneDXJSON(b []byte) ([]Component, error) {
+	var doc CycloneDX
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc.components(), nil
+}
+
+func cycloneDXXML(b []byte) ([]Component, error) {
+	var doc CycloneDX
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc.components(), nil
+}
+
+func (doc CycloneDX) components() []Component {
+	var components []Component
+	var walk func(cs []cdxComponent)
+	walk = func(cs []cdxComponent) {
+		for _, c := range cs {
+			name := c.Name
+			if c.Group != "" {
+				name = c.Group + "/" + c.Name
+			}
+			components = append(components, Component{
+				Ref:      c.Ref,
+				Name:     name,
+				Version:  c.Version,
+				PURL:     c.PURL,
+				Licenses: c.Licenses.SPDX(),
+			})
+			walk(c.Components)
+		}
+	}
+	walk(doc.Components)
+
+	dependsOn := map[string][]string{}
+	for _, d := range doc.Dependencies {
+		dependsOn[d.Ref] = append(dependsOn[d.Ref], d.DependsOn...)
+		for _, dep := range d.Dependency {
+			dependsOn[d.Ref] = append(dependsOn[d.Ref], dep.Ref)
+		}
+	}
+	return markDirect(components, []string{doc.Metadata.Component.Ref}, dependsOn)
+}
@@ -0,0 +1,107 @@
+package sbom
+
+import (
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"net/url"
+	"strings"
+)
+
+// PURL is a package url, pkg:type/namespace/name@version?qualifiers#subpath
+// ref. https://github.com/package-url/purl-spec/blob/master/PURL-SPECIFICATION.rst
+type PURL struct {
+	Type       string
+	Namespace  string
+	Name       string
+	Version    string
+	Qualifiers url.Values
+}
+
+func ParsePURL(s string) (PURL, error) {
+	var p PURL
+	rest, found := strings.CutPrefix(strings.TrimSpace(s), "pkg:")
+	if !found {
+		return p, fmt.Errorf("invalid purl %q", s)
+	}
+	rest, _, _ = strings.Cut(rest, "#")
+	rest, query, _ := strings.Cut(rest, "?")
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return p, fmt.Errorf("invalid purl %q: %w", s, err)
+	}
+	p.Qualifiers = q
+
+	// the version may contain @ encoded, but the last unencoded @ separates it
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		if p.Version, err = url.PathUnescape(rest[i+1:]); err != nil {
+			return p, fmt.Errorf("invalid purl %q: %w", s, err)
+		}
+		rest = rest[:i]
+	}
+
+	segments := strings.Split(strings.Trim(rest, "/"), "/")
+	if len(segments) < 2 {
+		return p, fmt.Errorf("invalid purl %q", s)
+	}
+	p.Type = strings.ToLower(segments[0])
+	for i, seg := range segments {
+		if segments[i], err = url.PathUnescape(seg); err != nil {
+			return p, fmt.Errorf("invalid purl %q: %w", s, err)
+		}
+	}
+	p.Name = segments[len(segments)-1]
+	p.Namespace = strings.Join(segments[1:len(segments)-1], "/")
+	return p, nil
+}
+
+// Dep returns the dep type and name depot uses for the package, names as in the lockfiles of the ecosystem
+func (p PURL) Dep() (depsdev.DepType, string, string, bool) {
+	join := func(sep string) string {
+		if p.Namespace == "" {
+			return p.Name
+		}
+		return p.Namespace + sep + p.Name
+	}
+
+	switch p.Type {
+	case "npm":
+		return depsdev.NPM, join("/"), p.Version, true
+	case "golang":
+		return depsdev.GO, join("/"), p.Version, true
+	case "maven":
+		return depsdev.MAVEN, join(":"), p.Version, true
+	case "cargo":
+		return depsdev.CARGO, p.Name, p.Version, true
+	case "pypi":
+		return depsdev.PYPI, p.Name, p.Version, true
+	case "nuget":
+		return depsdev.NUGET, p.Name, p.Version, true
+	case "gem":
+		v := p.Version
+		if platform := p.Qualifiers.Get("platform"); platform != "" && platform != "ruby" {
+			v += "-" + platform
+		}
+		return depsdev.RUBYGEMS, p.Name, v, true
+	case "composer":
+		return depsdev.COMPOSER, join("/"), p.Version, true
+	case "swift":
+		return depsdev.SWIFT, join("/"), p.Version, true
+	case "pub":
+		return depsdev.PUB, p.Name, p.Version, true
+	case "hex":
+		return depsdev.HEX, p.Name, p.Version, true
+	case "conan":
+		return depsdev.CONAN, p.Name, p.Version, true
+	case "deb":
+		return depsdev.DEB, p.Name, p.Version, true
+	case "apk":
+		return depsdev.APK, p.Name, p.Version, true
+	case "rpm":
+		v := p.Version
+		if epoch := p.Qualifiers.Get("epoch"); epoch != "" && epoch != "0" {
+			v = epoch + ":" + v
+		}
+		return depsdev.RPM, p.Name, v, true
+	}
+	return "", "", "", false
+}
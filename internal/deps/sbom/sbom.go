@@ -0,0 +1,68 @@
+package sbom
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Component is a package listed in an SBOM
+type Component struct {
+	Ref     string
+	Name    string
+	Version string
+	PURL    string
+	// Licenses are those stated in the SBOM, as SPDX identifiers or expressions where it gives them
+	Licenses []string
+	// Direct is true for components the subject of the SBOM depends on directly, or for all
+	// components if the SBOM does not describe the dependency graph
+	Direct bool
+}
+
+// IsSBOM tells if a file name follows the naming conventions of CycloneDX and SPDX documents
+func IsSBOM(name string) bool {
+	name = strings.ToLower(name)
+	for _, suffix := range []string{".cdx.json", ".cdx.xml", ".spdx.json", ".spdx"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return name == "bom.json" || name == "bom.xml"
+}
+
+// Read reads a CycloneDX json or xml, or SPDX json or tag-value document
+func Read(path string) ([]Component, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(b)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(b, []byte(`"bomFormat"`)):
+		return cycloneDXJSON(b)
+	case bytes.HasPrefix(trimmed, []byte("{")) && bytes.Contains(b, []byte(`"spdxVersion"`)):
+		return spdxJSON(b)
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return cycloneDXXML(b)
+	case bytes.HasPrefix(trimmed, []byte("SPDXVersion:")):
+		return spdxTagValue(b)
+	}
+	return nil, fmt.Errorf("%s is not a CycloneDX or SPDX document: %w", path, errors.ErrUnsupported)
+}
+
+// markDirect marks the components the root depends on directly, or all of them when there are no dependencies to go by
+func markDirect(components []Component, roots []string, dependsOn map[string][]string) []Component {
+	direct := map[string]bool{}
+	for _, root := range roots {
+		for _, ref := range dependsOn[root] {
+			direct[ref] = true
+		}
+	}
+	for i := range components {
+		components[i].Direct = len(direct) == 0 || direct[components[i].Ref]
+	}
+	return components
+}
@@ -0,0 +1,92 @@
+package sbom
+
+import (
+	"fmt"
+	"github.com/modfin/henry/slicez"
+	"strings"
+	"testing"
+)
+
+func summary(components []Component) []string {
+	return slicez.Map(components, func(c Component) string {
+		s := fmt.Sprintf("%s %s %s %s", c.PURL, c.Name, c.Version, strings.Join(c.Licenses, ","))
+		if !c.Direct {
+			return s + " //indirect"
+		}
+		return s
+	})
+}
+
+func TestRead(t *testing.T) {
+	tests := map[string][]string{
+		"testdata/app.cdx.json": {
+			"pkg:npm/express@4.18.2 express 4.18.2 MIT",
+			"pkg:npm/%40types/node@20.10.0 @types/node 20.10.0 MIT //indirect",
+			"pkg:maven/org.postgresql/postgresql@42.6.0 org.postgresql/postgresql 42.6.0 BSD-2-Clause",
+			"pkg:deb/debian/zlib1g@1:1.2.13.dfsg-1?arch=amd64 zlib1g 1:1.2.13.dfsg-1  //indirect",
+			" README.md   //indirect",
+		},
+		"testdata/app.cdx.xml": {
+			"pkg:cargo/serde@1.0.193 serde 1.0.193 MIT OR Apache-2.0",
+			"pkg:cargo/serde_derive@1.0.193 serde_derive 1.0.193 MIT,Apache-2.0 //indirect",
+			"pkg:cargo/acme-sdk@2.0.0 acme-sdk 2.0.0 ~non-standard //indirect",
+		},
+		"testdata/app.spdx.json": {
+			"pkg:pypi/requests@2.31.0 requests 2.31.0 Apache-2.0",
+			"pkg:pypi/urllib3@2.1.0 urllib3 2.1.0 MIT //indirect",
+			"pkg:apk/alpine/musl@1.2.4-r2?arch=x86_64 musl 1.2.4-r2  //indirect",
+		},
+		// without relationships every package is considered direct
+		"testdata/app.spdx": {
+			"pkg:rpm/fedora/openssl@3.0.11-1?arch=x86_64&epoch=1 openssl 3.0.11-1 Apache-2.0",
+			"pkg:npm/left-pad@1.3.0 left-pad 1.3.0 ",
+		},
+	}
+	for path, want := range tests {
+		components, err := Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := summary(components); !slicez.Equal(got, want) {
+			t.Fatalf("%s: expected %q, got %q", path, want, got)
+		}
+	}
+}
+
+func TestPURLDep(t *testing.T) {
+	tests := map[string]string{
+		"pkg:npm/%40angular/core@17.0.0":                           "npm @angular/core 17.0.0",
+		"pkg:golang/github.com/modfin/henry@v0.0.0-2023":           "go github.com/modfin/henry v0.0.0-2023",
+		"pkg:maven/org.postgresql/postgresql@42.6.0?type=jar":      "maven org.postgresql:postgresql 42.6.0",
+		"pkg:gem/nokogiri@1.15.4?platform=x86_64-linux":            "rubygems nokogiri 1.15.4-x86_64-linux",
+		"pkg:gem/rake@13.1.0?platform=ruby":                        "rubygems rake 13.1.0",
+		"pkg:composer/monolog/monolog@3.5.0":                       "composer monolog/monolog 3.5.0",
+		"pkg:swift/github.com/apple/swift-nio@2.62.0":              "swift github.com/apple/swift-nio 2.62.0",
+		"pkg:rpm/fedora/openssl@3.0.11-1?arch=x86_64&epoch=1":      "rpm openssl 1:3.0.11-1",
+		"pkg:deb/debian/curl@7.88.1-10?arch=amd64&distro=bookworm": "deb curl 7.88.1-10",
+	}
+	for s, want := range tests {
+		p, err := ParsePURL(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		depType, name, version, ok := p.Dep()
+		if !ok {
+			t.Fatalf("%s: expected a dep type", s)
+		}
+		if got := fmt.Sprintf("%s %s %s", depType, name, version); got != want {
+			t.Fatalf("%s: expected %q, got %q", s, want, got)
+		}
+	}
+
+	p, err := ParsePURL("pkg:github/actions/checkout@v4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, ok := p.Dep(); ok {
+		t.Fatalf("expected github purls to have no dep type")
+	}
+	if _, err := ParsePURL("npm/left-pad@1.3.0"); err == nil {
+		t.Fatalf("expected an error for a purl without scheme")
+	}
+}
@@ -0,0 +1,166 @@
+package sbom
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// SPDX is the parts of an SPDX 2 json document naming packages and their licenses
+// ref. https://spdx.github.io/spdx-spec/v2.3/
+type SPDX struct {
+	DocumentDescribes []string      `json:"documentDescribes"`
+	Packages          []spdxPackage `json:"packages"`
+	Relationships     []struct {
+		Element string `json:"spdxElementId"`
+		Type    string `json:"relationshipType"`
+		Related string `json:"relatedSpdxElement"`
+	} `json:"relationships"`
+}
+
+type spdxPackage struct {
+	ID               string `json:"SPDXID"`
+	Name             string `json:"name"`
+	VersionInfo      string `json:"versionInfo"`
+	LicenseConcluded string `json:"licenseConcluded"`
+	LicenseDeclared  string `json:"licenseDeclared"`
+	ExternalRefs     []struct {
+		Category string `json:"referenceCategory"`
+		Type     string `json:"referenceType"`
+		Locator  string `json:"referenceLocator"`
+	} `json:"externalRefs"`
+}
+
+func (p spdxPackage) purl() string {
+	for _, ref := range p.ExternalRefs {
+		if ref.Type == "purl" {
+			return ref.Locator
+		}
+	}
+	return ""
+}
+
+// licenses prefers the license concluded by whoever wrote the document over the one the package declares
+func (p spdxPackage) licenses() []string {
+	for _, l := range []string{p.LicenseConcluded, p.LicenseDeclared} {
+		if l != "" && l != "NOASSERTION" && l != "NONE" {
+			return []string{l}
+		}
+	}
+	return nil
+}
+
+func spdxJSON(b []byte) ([]Component, error) {
+	var doc SPDX
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return doc.components(), nil
+}
+
+func (doc SPDX) components() []Component {
+	roots := doc.DocumentDescribes
+	dependsOn := map[string][]string{}
+	for _, r := range doc.Relationships {
+		switch r.Type {
+		case "DESCRIBES":
+			roots = append(roots, r.Related)
+		case "DESCRIBED_BY":
+			roots = append(roots, r.Element)
+		case "DEPENDS_ON":
+			dependsOn[r.Element] = append(dependsOn[r.Element], r.Related)
+		case "DEPENDENCY_OF":
+			dependsOn[r.Related] = append(dependsOn[r.Related], r.Element)
+		}
+	}
+	isRoot := map[string]bool{}
+	for _, r := range roots {
+		isRoot[r] = true
+	}
+
+	var components []Component
+	for _, p := range doc.Packages {
+		// the package the document describes is the subject, not a dependency
+		if isRoot[p.ID] {
+			continue
+		}
+		components = append(components, Component{
+			Ref:      p.ID,
+			Name:     p.Name,
+			Version:  p.VersionInfo,
+			PURL:     p.purl(),
+			Licenses: p.licenses(),
+		})
+	}
+	return markDirect(components, roots, dependsOn)
+}
+
+// spdxTagValue reads the tag-value format by converting it to the json structure, Tag: value lines
+// where values spanning lines are enclosed in <text></text>
+func spdxTagValue(b []byte) ([]Component, error) {
+	var doc SPDX
+	var pkg *spdxPackage
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	inText := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inText {
+			inText = !strings.Contains(line, "</text>")
+			continue
+		}
+		tag, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, "<text>") && !strings.Contains(value, "</text>") {
+			inText = true
+			continue
+		}
+
+		switch tag {
+		case "PackageName":
+			doc.Packages = append(doc.Packages, spdxPackage{Name: value})
+			pkg = &doc.Packages[len(doc.Packages)-1]
+		case "SPDXID":
+			if pkg != nil {
+				pkg.ID = value
+			}
+		case "PackageVersion":
+			if pkg != nil {
+				pkg.VersionInfo = value
+			}
+		case "PackageLicenseConcluded":
+			if pkg != nil {
+				pkg.LicenseConcluded = value
+			}
+		case "PackageLicenseDeclared":
+			if pkg != nil {
+				pkg.LicenseDeclared = value
+			}
+		case "ExternalRef":
+			// ExternalRef: PACKAGE-MANAGER purl pkg:npm/left-pad@1.3.0
+			fields := strings.Fields(value)
+			if pkg != nil && len(fields) == 3 {
+				pkg.ExternalRefs = append(pkg.ExternalRefs, struct {
+					Category string `json:"referenceCategory"`
+					Type     string `json:"referenceType"`
+					Locator  string `json:"referenceLocator"`
+				}{fields[0], fields[1], fields[2]})
+			}
+		case "Relationship":
+			fields := strings.Fields(value)
+			if len(fields) == 3 {
+				doc.Relationships = append(doc.Relationships, struct {
+					Element string `json:"spdxElementId"`
+					Type    string `json:"relationshipType"`
+					Related string `json:"relatedSpdxElement"`
+				}{fields[0], fields[1], fields[2]})
+			}
+		}
+	}
+	return doc.components(), scanner.Err()
+}
@@ -0,0 +1,118 @@
+package swift
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Resolved is Package.resolved as written by swift package manager and xcode. Version 1 nests the pins
+// under object and names them by package and repositoryURL, versions 2 and 3 list them by identity and location.
+type Resolved struct {
+	Version int `json:"version"`
+	Object  struct {
+		Pins []struct {
+			Package       string `json:"package"`
+			RepositoryURL string `json:"repositoryURL"`
+			State         State  `json:"state"`
+		} `json:"pins"`
+	} `json:"object"`
+	Pins []Pin `json:"pins"`
+}
+
+type Pin struct {
+	Identity string `json:"identity"`
+	// Kind is remoteSourceControl, localSourceControl or registry
+	Kind     string `json:"kind"`
+	Location string `json:"location"`
+	State    State  `json:"state"`
+}
+
+type State struct {
+	Branch   string `json:"branch"`
+	Revision string `json:"revision"`
+	Version  string `json:"version"`
+}
+
+// ReadResolved reads the pins of a Package.resolved of any version
+func ReadResolved(path string) ([]Pin, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Resolved
+	err = json.Unmarshal(b, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Version > 1 {
+		return r.Pins, nil
+	}
+	var pins []Pin
+	for _, p := range r.Object.Pins {
+		pins = append(pins, Pin{
+			Identity: strings.ToLower(p.Package),
+			Kind:     "remoteSourceControl",
+			Location: p.RepositoryURL,
+			State:    p.State,
+		})
+	}
+	return pins, nil
+}
+
+// Name is the location of the package without scheme and .git suffix, e.g. github.com/apple/swift-nio,
+// since identities are only unique within a project. Registry packages are named by their scope.name identity.
+func (p Pin) Name() string {
+	if p.Kind == "registry" || p.Location == "" {
+		return p.Identity
+	}
+	loc := p.Location
+	if u, err := url.Parse(loc); err == nil && u.Host != "" {
+		loc = u.Host + u.Path
+	} else if _, after, found := strings.Cut(loc, "@"); found {
+		// scp like git@github.com:apple/swift-nio.git
+		loc = strings.Replace(after, ":", "/", 1)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(loc, "/"), ".git")
+}
+
+// GetVersion is the version the package is pinned to, or the revision for packages following a branch
+func (p Pin) GetVersion() string {
+	if p.State.Version != "" {
+		return p.State.Version
+	}
+	return p.State.Revision
+}
+
+func (p Pin) Local() bool {
+	return p.Kind == "localSourceControl" || p.Kind == "fileSystem"
+}
+
+// Checkout is the directory swift package manager clones a package into, <project>/.build/checkouts/<repository name>
+func (p Pin) Checkout(projectDir string) string {
+	return filepath.Join(projectDir, ".build", "checkouts", filepath.Base(p.Name()))
+}
+
+var packageURLRegexp = regexp.MustCompile(`\.package\s*\(\s*(?:name:\s*"[^"]*"\s*,\s*)?(?:url|id):\s*"([^"]+)"`)
+
+// Dependencies reads the names of the packages a Package.swift depends on directly. The manifest is swift code,
+// so this only recognises the common .package(url: "...") and .package(id: "...") declarations.
+func Dependencies(packageSwift string) ([]string, error) {
+	b, err := os.ReadFile(packageSwift)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, m := range packageURLRegexp.FindAllStringSubmatch(string(b), -1) {
+		kind := "remoteSourceControl"
+		if !strings.Contains(m[1], "/") && strings.Contains(m[1], ".") {
+			kind = "registry"
+		}
+		names = append(names, Pin{Identity: m[1], Kind: kind, Location: m[1]}.Name())
+	}
+	return names, nil
+}
@@ -0,0 +1,51 @@
+package swift
+
+import (
+	"github.com/modfin/henry/slicez"
+	"testing"
+)
+
+func TestReadResolvedV1(t *testing.T) {
+	pins, err := ReadResolved("./testdata/v1/Package.resolved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 1 {
+		t.Fatalf("expected 1 pin, got %+v", pins)
+	}
+	p := pins[0]
+	if p.Name() != "github.com/apple/swift-argument-parser" || p.GetVersion() != "1.2.0" || p.Local() {
+		t.Fatalf("unexpected pin %+v", p)
+	}
+}
+
+func TestReadResolvedV2(t *testing.T) {
+	pins, err := ReadResolved("./testdata/v2/Package.resolved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(pins, func(p Pin) string {
+		if p.Local() {
+			return p.Name() + " local"
+		}
+		return p.Name() + " " + p.GetVersion()
+	})
+	want := []string{
+		"github.com/apple/swift-log 1.5.3",
+		"github.com/apple/swift-nio 702cd7c56d5d44eeba73fdf83918339b26dc855c",
+		"/src/shared local",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	names, err := Dependencies("./testdata/v2/Package.swift")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicez.Equal(names, []string{"github.com/apple/swift-log"}) {
+		t.Fatalf("unexpected dependencies %v", names)
+	}
+}
@@ -0,0 +1,13 @@
+// swift-tools-version:5.9
+import PackageDescription
+
+let package = Package(
+    name: "server",
+    dependencies: [
+        .package(url: "https://github.com/apple/swift-log.git", from: "1.5.0"),
+        .package(path: "/src/shared"),
+    ],
+    targets: [
+        .executableTarget(name: "server", dependencies: [.product(name: "Logging", package: "swift-log")]),
+    ]
+)
@@ -0,0 +1,158 @@
+package terraform
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DefaultRegistry is the host of provider sources given as namespace/type
+const DefaultRegistry = "registry.terraform.io"
+
+// Provider is a provider pinned in .terraform.lock.hcl
+type Provider struct {
+	// Source is the fully qualified address, registry.terraform.io/hashicorp/aws
+	Source  string
+	Version string
+}
+
+func (p Provider) Host() string {
+	host, _, _ := strings.Cut(p.Source, "/")
+	return host
+}
+
+var (
+	providerBlock = regexp.MustCompile(`^provider\s+"([^"]+)"\s*\{`)
+	versionAttr   = regexp.MustCompile(`^version\s*=\s*"([^"]+)"`)
+)
+
+// ReadLockFile reads the providers of a .terraform.lock.hcl. The lock file is written by terraform init
+// and only ever holds provider blocks with flat attributes, which is all that is parsed.
+func ReadLockFile(path string) ([]Provider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var providers []Provider
+	var current *Provider
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := providerBlock.FindStringSubmatch(line); m != nil {
+			providers = append(providers, Provider{Source: Normalize(m[1])})
+			current = &providers[len(providers)-1]
+			continue
+		}
+		if line == "}" {
+			current = nil
+			continue
+		}
+		if m := versionAttr.FindStringSubmatch(line); m != nil && current != nil {
+			current.Version = m[1]
+		}
+	}
+	return providers, scanner.Err()
+}
+
+// Normalize qualifies a provider source address with the default registry, and lower cases it as terraform does
+func Normalize(source string) string {
+	source = strings.ToLower(strings.TrimSpace(source))
+	switch strings.Count(source, "/") {
+	case 0:
+		return DefaultRegistry + "/hashicorp/" + source
+	case 1:
+		return DefaultRegistry + "/" + source
+	}
+	return source
+}
+
+var (
+	requiredProviders = regexp.MustCompile(`required_providers\s*\{`)
+	providerEntry     = regexp.MustCompile(`(?m)^\s*([\w-]+)\s*=\s*(\{|")`)
+	sourceAttr        = regexp.MustCompile(`source\s*=\s*"([^"]+)"`)
+)
+
+// Dependencies are the provider sources declared in the required_providers blocks of the .tf files of a module
+func Dependencies(moduleDir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(moduleDir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []string
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		content := string(b)
+		for _, loc := range requiredProviders.FindAllStringIndex(content, -1) {
+			block := content[loc[1]:closing(content, loc[1])]
+			for _, entry := range entries(block) {
+				source := entry.name
+				if m := sourceAttr.FindStringSubmatch(entry.body); m != nil {
+					source = m[1]
+				}
+				sources = append(sources, Normalize(source))
+			}
+		}
+	}
+	return sources, nil
+}
+
+type entry struct {
+	name string
+	body string
+}
+
+// entries splits a required_providers block into its name = { ... } and legacy name = "constraint" entries
+func entries(block string) []entry {
+	var es []entry
+	for _, loc := range providerEntry.FindAllStringSubmatchIndex(block, -1) {
+		// attributes nested in an entry, e.g. source and version, are not entries themselves
+		if depth(block[:loc[0]]) > 0 {
+			continue
+		}
+		e := entry{name: block[loc[2]:loc[3]]}
+		if block[loc[4]:loc[5]] == "{" {
+			e.body = block[loc[5]:closing(block, loc[5])]
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+func depth(s string) int {
+	return strings.Count(s, "{") - strings.Count(s, "}")
+}
+
+// closing finds the brace closing a block opened just before start
+func closing(s string, start int) int {
+	d := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			d++
+		case '}':
+			d--
+			if d == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// Installed is the directory terraform init unpacked a provider into, or empty if it is not installed.
+// Providers are installed per platform, .terraform/providers/<source>/<version>/<os>_<arch>.
+func Installed(moduleDir string, p Provider) string {
+	platforms, err := os.ReadDir(filepath.Join(moduleDir, ".terraform", "providers", filepath.FromSlash(p.Source), p.Version))
+	if err != nil || len(platforms) == 0 {
+		return ""
+	}
+	return filepath.Join(moduleDir, ".terraform", "providers", filepath.FromSlash(p.Source), p.Version, platforms[0].Name())
+}
@@ -0,0 +1,64 @@
+package terraform
+
+import (
+	"github.com/modfin/henry/slicez"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadLockFile(t *testing.T) {
+	providers, err := ReadLockFile("testdata/.terraform.lock.hcl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(providers, func(p Provider) string {
+		return p.Source + " " + p.Version
+	})
+	want := []string{
+		"registry.terraform.io/hashicorp/aws 5.31.0",
+		"registry.terraform.io/hashicorp/random 3.6.0",
+		"registry.terraform.io/integrations/github 5.42.0",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	got, err := Dependencies("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"registry.terraform.io/hashicorp/aws",
+		"registry.terraform.io/integrations/github",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	legacy := entries(`
+    aws = "~> 3.0"
+    google = {
+      version = "~> 4.0"
+    }
+`)
+	names := slicez.Map(legacy, func(e entry) string { return e.name })
+	if !slicez.Equal(names, []string{"aws", "google"}) {
+		t.Fatalf("expected aws and google, got %v", names)
+	}
+	if Normalize("Google") != "registry.terraform.io/hashicorp/google" {
+		t.Fatalf("expected implicit hashicorp namespace, got %s", Normalize("Google"))
+	}
+}
+
+func TestInstalled(t *testing.T) {
+	got := Installed("testdata", Provider{Source: "registry.terraform.io/hashicorp/random", Version: "3.6.0"})
+	want := filepath.Join("testdata", ".terraform", "providers", "registry.terraform.io", "hashicorp", "random", "3.6.0", "linux_amd64")
+	if got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	if got := Installed("testdata", Provider{Source: "registry.terraform.io/hashicorp/aws", Version: "5.31.0"}); got != "" {
+		t.Fatalf("expected aws not to be installed, got %s", got)
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/example/app
+
+go 1.22
+
+require (
+	github.com/example/lib v0.0.0-00010101000000-000000000000
+	github.com/example/vendored v1.2.0
+	github.com/example/missing v0.3.0
+)
+
+replace github.com/example/lib => ./lib
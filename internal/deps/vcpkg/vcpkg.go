@@ -0,0 +1,307 @@
+package vcpkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Manifest is vcpkg.json, of a project in manifest mode or of a port in the ports tree
+type Manifest struct {
+	Name            string       `json:"name"`
+	Dependencies    []Dependency `json:"dependencies"`
+	Overrides       []Override   `json:"overrides"`
+	BuiltinBaseline string       `json:"builtin-baseline"`
+	// License is an SPDX expression, set in port manifests
+	License *string `json:"license"`
+
+	Version       string `json:"version"`
+	VersionSemver string `json:"version-semver"`
+	VersionDate   string `json:"version-date"`
+	VersionString string `json:"version-string"`
+	PortVersion   int    `json:"port-version"`
+}
+
+// Dependency is either just the port name or an object with constraints
+type Dependency struct {
+	Name       string `json:"name"`
+	MinVersion string `json:"version>="`
+	Host       bool   `json:"host"`
+}
+
+func (d *Dependency) UnmarshalJSON(b []byte) error {
+	var name string
+	if json.Unmarshal(b, &name) == nil {
+		d.Name = name
+		return nil
+	}
+	type dependency Dependency
+	return json.Unmarshal(b, (*dependency)(d))
+}
+
+type Override struct {
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	VersionSemver string `json:"version-semver"`
+	VersionDate   string `json:"version-date"`
+	VersionString string `json:"version-string"`
+	PortVersion   int    `json:"port-version"`
+}
+
+func (o Override) GetVersion() string {
+	return Version(coalesce(o.Version, o.VersionSemver, o.VersionDate, o.VersionString), o.PortVersion)
+}
+
+func (m Manifest) GetVersion() string {
+	return Version(coalesce(m.Version, m.VersionSemver, m.VersionDate, m.VersionString), m.PortVersion)
+}
+
+// Configuration is vcpkg-configuration.json, which may carry the baseline instead of vcpkg.json
+type Configuration struct {
+	DefaultRegistry *struct {
+		Kind       string `json:"kind"`
+		Baseline   string `json:"baseline"`
+		Repository string `json:"repository"`
+	} `json:"default-registry"`
+}
+
+// Version is written as vcpkg does, with a port version other than 0 following a #, e.g. 1.3#1
+func Version(version string, portVersion int) string {
+	if portVersion == 0 {
+		return version
+	}
+	return fmt.Sprintf("%s#%d", version, portVersion)
+}
+
+func ReadManifest(path string) (Manifest, error) {
+	var m Manifest
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+// Baseline returns the commit of the builtin registry versions are resolved at,
+// from vcpkg.json or the builtin default registry of vcpkg-configuration.json next to it
+func (m Manifest) Baseline(projectDir string) string {
+	if m.BuiltinBaseline != "" {
+		return m.BuiltinBaseline
+	}
+	b, err := os.ReadFile(filepath.Join(projectDir, "vcpkg-configuration.json"))
+	if err != nil {
+		return ""
+	}
+	var c Configuration
+	if json.Unmarshal(b, &c) != nil || c.DefaultRegistry == nil {
+		return ""
+	}
+	if c.DefaultRegistry.Kind == "builtin" || c.DefaultRegistry.Kind == "" {
+		return c.DefaultRegistry.Baseline
+	}
+	return ""
+}
+
+// Root is the vcpkg installation with the ports tree, $VCPKG_ROOT
+func Root() string {
+	return os.Getenv("VCPKG_ROOT")
+}
+
+// Head is the commit the vcpkg root is checked out at
+func Head(root string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(root, ".git", "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	head := strings.TrimSpace(string(b))
+	ref, found := strings.CutPrefix(head, "ref: ")
+	if !found {
+		return head, nil
+	}
+	if b, err := os.ReadFile(filepath.Join(root, ".git", filepath.FromSlash(ref))); err == nil {
+		return strings.TrimSpace(string(b)), nil
+	}
+	packed, err := os.ReadFile(filepath.Join(root, ".git", "packed-refs"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(packed), "\n") {
+		if sha, name, found := strings.Cut(strings.TrimSpace(line), " "); found && name == ref {
+			return sha, nil
+		}
+	}
+	return "", fmt.Errorf("could not resolve %s in %s", ref, root)
+}
+
+// Baseline reads versions/baseline.json of the ports tree, the version of every port at the checked out commit
+func Baseline(root string) (map[string]string, error) {
+	b, err := os.ReadFile(filepath.Join(root, "versions", "baseline.json"))
+	if err != nil {
+		return nil, err
+	}
+	var baseline struct {
+		Default map[string]struct {
+			Baseline    string `json:"baseline"`
+			PortVersion int    `json:"port-version"`
+		} `json:"default"`
+	}
+	err = json.Unmarshal(b, &baseline)
+	if err != nil {
+		return nil, err
+	}
+	versions := map[string]string{}
+	for name, v := range baseline.Default {
+		versions[name] = Version(v.Baseline, v.PortVersion)
+	}
+	return versions, nil
+}
+
+// Resolve returns the versions vcpkg picks for the dependencies of a manifest given the baseline, the override
+// if there is one, otherwise the highest of the baseline and the minimum version asked for. Ports the baseline
+// does not know are left out.
+func (m Manifest) Resolve(baseline map[string]string) map[string]string {
+	versions := map[string]string{}
+	for _, d := range m.Dependencies {
+		v, ok := baseline[d.Name]
+		if d.MinVersion != "" && (!ok || less(v, d.MinVersion)) {
+			v, ok = d.MinVersion, true
+		}
+		if ok {
+			versions[d.Name] = v
+		}
+	}
+	for _, o := range m.Overrides {
+		versions[o.Name] = o.GetVersion()
+	}
+	return versions
+}
+
+// less compares versions by their dot separated numbers and then port version, as vcpkg does for
+// version and version-semver, schemes it can not compare that way are considered equal
+func less(a string, b string) bool {
+	av, ap, _ := strings.Cut(a, "#")
+	bv, bp, _ := strings.Cut(b, "#")
+	as, bs := strings.Split(av, "."), strings.Split(bv, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		var err error
+		if i < len(as) {
+			if x, err = strconv.Atoi(as[i]); err != nil {
+				return false
+			}
+		}
+		if i < len(bs) {
+			if y, err = strconv.Atoi(bs[i]); err != nil {
+				return false
+			}
+		}
+		if x != y {
+			return x < y
+		}
+	}
+	x, _ := strconv.Atoi(ap)
+	y, _ := strconv.Atoi(bp)
+	return x < y
+}
+
+// Package is a port installed into vcpkg_installed, as recorded in its status database
+type Package struct {
+	Name    string
+	Version string
+	Triplet string
+}
+
+// Installed reads vcpkg_installed/vcpkg/status, in the debian control format, of a project in manifest mode.
+// It lists every port installed, including those depended on indirectly.
+func Installed(projectDir string) ([]Package, error) {
+	f, err := os.Open(filepath.Join(projectDir, "vcpkg_installed", "vcpkg", "status"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pkgs []Package
+	fields := map[string]string{}
+	flush := func() {
+		// feature paragraphs repeat the package, only the core paragraph carries the version
+		if fields["Package"] != "" && fields["Feature"] == "" && strings.HasSuffix(fields["Status"], " installed") {
+			portVersion, _ := strconv.Atoi(fields["Port-Version"])
+			pkgs = append(pkgs, Package{
+				Name:    fields["Package"],
+				Version: Version(fields["Version"], portVersion),
+				Triplet: fields["Architecture"],
+			})
+		}
+		fields = map[string]string{}
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		if key, value, found := strings.Cut(line, ":"); found && !strings.HasPrefix(line, " ") {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+	flush()
+	return pkgs, scanner.Err()
+}
+
+// InstalledLicense reads the license vcpkg declares in the SBOM it writes for an installed port,
+// vcpkg_installed/<triplet>/share/<port>/vcpkg.spdx.json
+func InstalledLicense(projectDir string, p Package) (string, error) {
+	b, err := os.ReadFile(filepath.Join(projectDir, "vcpkg_installed", p.Triplet, "share", p.Name, "vcpkg.spdx.json"))
+	if err != nil {
+		return "", err
+	}
+	var sbom struct {
+		Packages []struct {
+			Name            string `json:"name"`
+			LicenseDeclared string `json:"licenseDeclared"`
+		} `json:"packages"`
+	}
+	err = json.Unmarshal(b, &sbom)
+	if err != nil {
+		return "", err
+	}
+	for _, pkg := range sbom.Packages {
+		if pkg.Name == p.Name && pkg.LicenseDeclared != "" && pkg.LicenseDeclared != "NOASSERTION" {
+			return pkg.LicenseDeclared, nil
+		}
+	}
+	return "", fmt.Errorf("no license declared for %s", p.Name)
+}
+
+// PortLicense reads the license of a port from ports/<port>/vcpkg.json in the ports tree,
+// given that the port is at the version asked for
+func PortLicense(root string, name string, version string) (string, error) {
+	path := filepath.Join(root, "ports", name, "vcpkg.json")
+	m, err := ReadManifest(path)
+	if err != nil {
+		return "", err
+	}
+	if m.GetVersion() != version {
+		return "", fmt.Errorf("%s is at %s, not %s", path, m.GetVersion(), version)
+	}
+	if m.License == nil || *m.License == "" {
+		return "", fmt.Errorf("no license in %s", path)
+	}
+	return *m.License, nil
+}
+
+func coalesce(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package vcpkg
+
+import (
+	"github.com/modfin/henry/slicez"
+	"testing"
+)
+
+func TestInstalled(t *testing.T) {
+	pkgs, err := Installed("./testdata/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Map(pkgs, func(p Package) string {
+		return p.Name + " " + p.Version + " " + p.Triplet
+	})
+	want := []string{
+		"vcpkg-cmake 2023-05-04 x64-linux",
+		"fmt 10.0.0 x64-linux",
+		"zlib 1.3#1 x64-linux",
+	}
+	if !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	l, err := InstalledLicense("./testdata/project", pkgs[1])
+	if err != nil || l != "MIT" {
+		t.Fatalf("expected MIT, got %q %v", l, err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	m, err := ReadManifest("./testdata/project/vcpkg.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := Baseline("./testdata/root")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions := m.Resolve(baseline)
+	// fmt is overridden, the baseline of zlib is above the minimum asked for
+	for name, want := range map[string]string{"fmt": "10.0.0", "zlib": "1.3#1"} {
+		if versions[name] != want {
+			t.Fatalf("expected %s at %s, got %s", name, want, versions[name])
+		}
+	}
+
+	versions = m.Resolve(map[string]string{"zlib": "1.2.11"})
+	if versions["zlib"] != "1.2.13" {
+		t.Fatalf("expected zlib at the minimum version, got %s", versions["zlib"])
+	}
+}
+
+func TestPortLicense(t *testing.T) {
+	l, err := PortLicense("./testdata/root", "zlib", "1.3#1")
+	if err != nil || l != "Zlib" {
+		t.Fatalf("expected Zlib, got %q %v", l, err)
+	}
+	if _, err := PortLicense("./testdata/root", "zlib", "1.2.13"); err == nil {
+		t.Fatal("expected the ports tree not to have zlib 1.2.13")
+	}
+}
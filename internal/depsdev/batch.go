@@ -0,0 +1,120 @@
+package depsdev
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// MaxBatch is the most versions deps.dev answers for in a single batch request
+const MaxBatch = 5000
+
+// VersionKey names a package version in a batch request, the system is the upper case dep type
+type VersionKey struct {
+	System  string `json:"system"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Key is the version key of a package version
+func Key(depType DepType, name string, version string) VersionKey {
+	return VersionKey{System: strings.ToUpper(string(depType)), Name: name, Version: version}
+}
+
+// BatchRequest is the body of a GetVersionBatch request, continued by the page token of the previous response
+// ref. https://docs.deps.dev/api/v3alpha/#getversionbatch
+type BatchRequest struct {
+	Requests []struct {
+		VersionKey VersionKey `json:"versionKey"`
+	} `json:"requests"`
+	PageToken string `json:"pageToken,omitempty"`
+}
+
+// BatchResponse answers the requests of a batch, without a version for those deps.dev does not know
+type BatchResponse struct {
+	Responses []struct {
+		Request struct {
+			VersionKey VersionKey `json:"versionKey"`
+		} `json:"request"`
+		Version *Version `json:"version"`
+	} `json:"responses"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// batched are the versions fetched in batches, answering Version without a request of its own. Versions deps.dev
+// does not know are kept as nil.
+type batched struct {
+	mu       sync.Mutex
+	versions map[VersionKey]*Version
+}
+
+func (b *batched) get(key VersionKey) (*Version, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, found := b.versions[key]
+	return v, found
+}
+
+func (b *batched) put(key VersionKey, v *Version) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.versions[key] = v
+}
+
+// Batch fetches many versions in as few requests as deps.dev allows, MaxBatch at a time. Version, and Licenses,
+// then answer for them without requests of their own.
+func (c *Client) Batch(keys []VersionKey) error {
+	for len(keys) > 0 {
+		n := min(len(keys), MaxBatch)
+		if err := c.batch(keys[:n]); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+func (c *Client) batch(keys []VersionKey) error {
+	var req BatchRequest
+	for _, k := range keys {
+		req.Requests = append(req.Requests, struct {
+			VersionKey VersionKey `json:"versionKey"`
+		}{k})
+	}
+
+	answered := map[VersionKey]*Version{}
+	for {
+		body, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		res, err := c.fetch.Post(c.uri+"/versionbatch", body)
+		if err != nil {
+			return err
+		}
+		var page BatchResponse
+		if res.StatusCode > 299 {
+			_ = res.Body.Close()
+			return StatusError(res.StatusCode)
+		}
+		err = json.NewDecoder(res.Body).Decode(&page)
+		_ = res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, r := range page.Responses {
+			answered[r.Request.VersionKey] = r.Version
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		req.PageToken = page.NextPageToken
+	}
+
+	// versions not answered for, or without a version, are not known to deps.dev
+	for _, k := range keys {
+		c.batched.put(k, answered[k])
+	}
+	return nil
+}
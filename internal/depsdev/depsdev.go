@@ -2,9 +2,12 @@ package depsdev
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/modfin/depot/internal/fetch"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 type DepType string
@@ -13,16 +16,62 @@ const NPM DepType = "npm"
 const GO DepType = "go"
 const MAVEN DepType = "maven"
 const CARGO DepType = "cargo"
+const NUGET DepType = "nuget"
+const RUBYGEMS DepType = "rubygems"
+const SWIFT DepType = "swift"
+const PUB DepType = "pub"
+const HEX DepType = "hex"
+
+// COMPOSER is not known to deps.dev, composer.lock carries the licenses itself
+const COMPOSER DepType = "composer"
+
+// VCPKG and CONAN are not known to deps.dev, licenses are read from the port and recipe metadata
+const VCPKG DepType = "vcpkg"
+const CONAN DepType = "conan"
+
+// DEB, APK and RPM are operating system packages, their licenses are read from the package databases
+const DEB DepType = "deb"
+const APK DepType = "apk"
+const RPM DepType = "rpm"
+
+// TERRAFORM providers and HELM charts are not known to deps.dev, licenses are looked up through their registries
+const TERRAFORM DepType = "terraform"
+const HELM DepType = "helm"
+
+// ACTIONS are github actions and reusable workflows, named by their repository
+const ACTIONS DepType = "actions"
 
 const PYPI DepType = "pypi"
 
+// Types are all dep types depot reads
+var Types = []DepType{NPM, GO, MAVEN, CARGO, NUGET, PYPI, RUBYGEMS, SWIFT, PUB, HEX, COMPOSER, VCPKG, CONAN, DEB, APK, RPM, TERRAFORM, HELM, ACTIONS}
+
+// DefaultURL is the deps.dev api depot asks
+const DefaultURL = "https://api.deps.dev/v3alpha"
+
+// ErrNotFound is the error of package versions not known, license providers report versions they do not know with it
+var ErrNotFound = errors.New("http status 404")
+
+// StatusError is the error of a response failing with an http status, ErrNotFound for 404
+func StatusError(status int) error {
+	if status == http.StatusNotFound {
+		return ErrNotFound
+	}
+	return fmt.Errorf("http status %d", status)
+}
+
+// Client asks deps.dev, its requests made as those of fetch.Client are
 type Client struct {
-	uri string
+	uri     string
+	fetch   fetch.Client
+	batched *batched
 }
 
 func New() *Client {
 	return &Client{
-		uri: "https://api.deps.dev/v3alpha",
+		uri:     DefaultURL,
+		fetch:   *fetch.New(),
+		batched: &batched{versions: map[VersionKey]*Version{}},
 	}
 }
 
@@ -39,16 +88,80 @@ func New() *Client {
 //https://api.deps.dev/v3alpha/systems/cargo/packages/rand/versions/0.8.5
 //
 
+// WithURL points the client at another deps.dev api, or a stand-in in tests
+func (c *Client) WithURL(uri string) *Client {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests to deps.dev as the given client, with its http client, user agent, retries and
+// rate limits
+func (c *Client) WithFetch(client *fetch.Client) *Client {
+	c.fetch = *client
+	return c
+}
+
+// Fetch is the client making the requests to deps.dev, a copy to make those to other hosts with
+func (c *Client) Fetch() *fetch.Client {
+	return c.fetch.Clone()
+}
+
+// WithRetries retries failing requests at most this many times, 0 never retries them
+func (c *Client) WithRetries(retries int) *Client {
+	c.fetch.WithRetries(retries)
+	return c
+}
+
+// WithBackoff waits at most backoff, doubling with every attempt up to maxBackoff, before retrying a request
+func (c *Client) WithBackoff(backoff time.Duration, maxBackoff time.Duration) *Client {
+	c.fetch.WithBackoff(backoff, maxBackoff)
+	return c
+}
+
+// WithRateLimit makes at most perSecond requests a second, 0 or less makes them as fast as they come
+func (c *Client) WithRateLimit(perSecond float64) *Client {
+	c.fetch.WithRateLimit(perSecond)
+	return c
+}
+
+// Clone is a copy of the client, sharing its http client and rate limits, to be pointed elsewhere without affecting
+// the original. Versions fetched in batches are not shared, they are those of another api.
+func (c *Client) Clone() *Client {
+	clone := *c
+	clone.batched = &batched{versions: map[VersionKey]*Version{}}
+	return &clone
+}
+
+// Serves tells if deps.dev knows the ecosystem of a dep type
+func Serves(depType DepType) bool {
+	switch depType {
+	case NPM, GO, MAVEN, CARGO, NUGET, PYPI:
+		return true
+	}
+	return false
+}
+
+func (c *Client) Name() string {
+	return "deps.dev"
+}
+
 func (c *Client) Version(depType DepType, name string, version string) (Version, error) {
 	var v Version
-	res, err := http.DefaultClient.Get(fmt.Sprintf("%s/systems/%s/packages/%s/versions/%s", c.uri, url.PathEscape(string(depType)), url.PathEscape(name), url.PathEscape(version)))
+	if batched, found := c.batched.get(Key(depType, name, version)); found {
+		if batched == nil {
+			return v, ErrNotFound
+		}
+		return *batched, nil
+	}
+
+	res, err := c.fetch.Get(fmt.Sprintf("%s/systems/%s/packages/%s/versions/%s", c.uri, url.PathEscape(string(depType)), url.PathEscape(name), url.PathEscape(version)), nil)
 	if err != nil {
 		return v, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode > 299 {
-		return v, fmt.Errorf("http status %d", res.StatusCode)
+		return v, StatusError(res.StatusCode)
 	}
 
 	err = json.NewDecoder(res.Body).Decode(&v)
@@ -0,0 +1,167 @@
+// Package depsdevtest is an in-process fake of the deps.dev api, for testing depot offline
+package depsdevtest
+
+import (
+	"encoding/json"
+	"github.com/modfin/depot/internal/depsdev"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server answers the version requests of depsdev.Client with the licenses added to it, and 404 for versions
+// it does not know, as deps.dev does
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	versions map[string]depsdev.Version
+	failures map[string][]int
+	requests []string
+	batches  int
+}
+
+// PageSize is how many versions a batch response answers for, the rest are answered on following pages
+const PageSize = 100
+
+func key(depType depsdev.DepType, name string, version string) string {
+	return string(depType) + "|" + name + "|" + version
+}
+
+// NewServer starts a fake deps.dev, to be closed by the caller. Point clients at it by its URL.
+func NewServer() *Server {
+	s := &Server{versions: map[string]depsdev.Version{}, failures: map[string][]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Add makes a package version known, with its licenses
+func (s *Server) Add(depType depsdev.DepType, name string, version string, licenses ...string) *Server {
+	var v depsdev.Version
+	v.VersionKey.System = strings.ToUpper(string(depType))
+	v.VersionKey.Name = name
+	v.VersionKey.Version = version
+	v.Licenses = licenses
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.versions[key(depType, name, version)] = v
+	return s
+}
+
+// Fail answers the next requests for a package version with the given statuses, before answering as usual.
+// 429 is answered asking to retry after a second.
+func (s *Server) Fail(depType depsdev.DepType, name string, version string, statuses ...int) *Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := key(depType, name, version)
+	s.failures[k] = append(s.failures[k], statuses...)
+	return s
+}
+
+// Requests are the package versions asked for so far, as <type>|<name>|<version>
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+// Batches is how many batch requests were made so far, counting every page
+func (s *Server) Batches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+// serve answers /systems/<type>/packages/<name>/versions/<version>, names and versions path escaped, and batches
+// of versions posted to /versionbatch
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && r.URL.Path == "/versionbatch" {
+		s.serveBatch(w, r)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	if r.Method != http.MethodGet || len(parts) != 6 || parts[0] != "systems" || parts[2] != "packages" || parts[4] != "versions" {
+		http.NotFound(w, r)
+		return
+	}
+	var unescaped []string
+	for _, i := range []int{1, 3, 5} {
+		p, err := url.PathUnescape(parts[i])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		unescaped = append(unescaped, p)
+	}
+	k := key(depsdev.DepType(unescaped[0]), unescaped[1], unescaped[2])
+
+	s.mu.Lock()
+	s.requests = append(s.requests, k)
+	v, found := s.versions[k]
+	status := 0
+	if failures := s.failures[k]; len(failures) > 0 {
+		status, s.failures[k] = failures[0], failures[1:]
+	}
+	s.mu.Unlock()
+
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "1")
+	}
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// serveBatch answers a page of a batch, the page token being the offset of the page among the requests
+func (s *Server) serveBatch(w http.ResponseWriter, r *http.Request) {
+	var req depsdev.BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset := 0
+	if req.PageToken != "" {
+		var err error
+		if offset, err = strconv.Atoi(req.PageToken); err != nil || offset > len(req.Requests) {
+			http.Error(w, "invalid page token", http.StatusBadRequest)
+			return
+		}
+	}
+	end := min(offset+PageSize, len(req.Requests))
+
+	var res depsdev.BatchResponse
+	s.mu.Lock()
+	s.batches++
+	for _, q := range req.Requests[offset:end] {
+		k := key(depsdev.DepType(strings.ToLower(q.VersionKey.System)), q.VersionKey.Name, q.VersionKey.Version)
+		var answer struct {
+			Request struct {
+				VersionKey depsdev.VersionKey `json:"versionKey"`
+			} `json:"request"`
+			Version *depsdev.Version `json:"version"`
+		}
+		answer.Request.VersionKey = q.VersionKey
+		if v, found := s.versions[k]; found {
+			answer.Version = &v
+		}
+		res.Responses = append(res.Responses, answer)
+	}
+	s.mu.Unlock()
+
+	if end < len(req.Requests) {
+		res.NextPageToken = strconv.Itoa(end)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(res)
+}
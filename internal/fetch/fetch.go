@@ -0,0 +1,251 @@
+// Package fetch makes the http requests of depot, to deps.dev and the package registries, within a timeout and a
+// rate limit per host, retrying those failing for the time being
+package fetch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout bounds every request, a request hanging would otherwise hang depot
+const DefaultTimeout = 30 * time.Second
+
+// DefaultUserAgent identifies depot to deps.dev and the registries
+const DefaultUserAgent = "depot (+https://github.com/modfin/depot)"
+
+// DefaultRetries is how many times a request failing with a server error, 429, a timeout or a reset connection is
+// retried
+const DefaultRetries = 3
+
+// DefaultRateLimit is how many requests per second depot makes to a host at most
+const DefaultRateLimit = 20
+
+const (
+	// defaultBackoff is the ceiling of the first wait before a retry, doubling with every attempt
+	defaultBackoff = 250 * time.Millisecond
+	// defaultMaxBackoff caps the wait before a retry, as well as how long a Retry-After is honoured
+	defaultMaxBackoff = 30 * time.Second
+)
+
+type Client struct {
+	http      *http.Client
+	userAgent string
+
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+	limits     *limits
+}
+
+func New() *Client {
+	return &Client{
+		http:       &http.Client{Timeout: DefaultTimeout},
+		userAgent:  DefaultUserAgent,
+		retries:    DefaultRetries,
+		backoff:    defaultBackoff,
+		maxBackoff: defaultMaxBackoff,
+		limits:     newLimits(DefaultRateLimit),
+	}
+}
+
+// NewHTTPClient is an http client timing out requests after timeout, going through proxy if given, or else the
+// proxy of the environment, HTTPS_PROXY and NO_PROXY
+func NewHTTPClient(timeout time.Duration, proxy string) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %q: %w", proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// WithHTTPClient makes the requests with another http client, e.g. one from NewHTTPClient
+func (c *Client) WithHTTPClient(client *http.Client) *Client {
+	c.http = client
+	return c
+}
+
+// WithUserAgent identifies depot by another user agent
+func (c *Client) WithUserAgent(userAgent string) *Client {
+	c.userAgent = userAgent
+	return c
+}
+
+// WithRetries retries failing requests at most this many times, 0 never retries them
+func (c *Client) WithRetries(retries int) *Client {
+	c.retries = retries
+	return c
+}
+
+// WithBackoff waits at most backoff, doubling with every attempt up to maxBackoff, before retrying a request
+func (c *Client) WithBackoff(backoff time.Duration, maxBackoff time.Duration) *Client {
+	c.backoff = backoff
+	c.maxBackoff = maxBackoff
+	return c
+}
+
+// WithRateLimit makes at most perSecond requests a second to a host, 0 or less makes them as fast as they come
+func (c *Client) WithRateLimit(perSecond float64) *Client {
+	c.limits = newLimits(perSecond)
+	return c
+}
+
+// Clone is a copy of the client, to be configured without affecting the original. The rate limits of the hosts
+// are shared, until another rate limit is set.
+func (c *Client) Clone() *Client {
+	clone := *c
+	return &clone
+}
+
+// limits are the rate limits of the hosts requested, shared by the clones of a client
+type limits struct {
+	mu       sync.Mutex
+	interval time.Duration
+	hosts    map[string]*limiter
+}
+
+func newLimits(perSecond float64) *limits {
+	if perSecond <= 0 {
+		return &limits{}
+	}
+	return &limits{interval: time.Duration(float64(time.Second) / perSecond), hosts: map[string]*limiter{}}
+}
+
+func (l *limits) of(host string) *limiter {
+	if l.interval == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, found := l.hosts[host]; !found {
+		l.hosts[host] = &limiter{interval: l.interval}
+	}
+	return l.hosts[host]
+}
+
+// limiter spaces the requests to a host evenly
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *limiter) wait() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(at))
+}
+
+func retriable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// transient is whether a request failing with err may succeed if retried, it timed out or the connection was reset.
+// Others, e.g. unknown hosts or certificates not trusted, fail again however often they are retried.
+func transient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryAfter is how long a server asks to be left alone for, in seconds or until a date, 0 if it does not say
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// delay is how long to wait before retrying after attempt, as the server asked or else exponentially backing
+// off with full jitter, so that many clients failing together do not retry together
+func (c *Client) delay(attempt int, after time.Duration) time.Duration {
+	if after > 0 {
+		return min(after, c.maxBackoff)
+	}
+	ceiling := c.maxBackoff
+	if attempt < 32 {
+		ceiling = min(c.backoff<<attempt, c.maxBackoff)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling)
+}
+
+// Get requests a url within the rate limit of its host, retrying server errors, 429, timeouts and reset connections.
+// The response of the last attempt is returned, whatever its status.
+func (c *Client) Get(u string, header http.Header) (*http.Response, error) {
+	return c.do(http.MethodGet, u, header, nil)
+}
+
+// Post sends a json body to a url, retried as Get is
+func (c *Client) Post(u string, body []byte) (*http.Response, error) {
+	return c.do(http.MethodPost, u, http.Header{"Content-Type": {"application/json"}}, body)
+}
+
+func (c *Client) do(method string, u string, header http.Header, body []byte) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, u, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+
+		c.limits.of(req.URL.Host).wait()
+		res, err := c.http.Do(req)
+		if err == nil && !retriable(res.StatusCode) {
+			return res, nil
+		}
+		if err != nil && !transient(err) {
+			return nil, err
+		}
+		if attempt >= c.retries {
+			if err != nil {
+				return nil, fmt.Errorf("%w, after %d attempts", err, attempt+1)
+			}
+			return res, nil
+		}
+
+		var after time.Duration
+		if err == nil {
+			after = retryAfter(res.Header.Get("Retry-After"), time.Now())
+			_, _ = io.Copy(io.Discard, res.Body)
+			_ = res.Body.Close()
+		}
+		time.Sleep(c.delay(attempt, after))
+	}
+}
@@ -0,0 +1,116 @@
+package fetch
+
+import (
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	for _, test := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	} {
+		if got := retryAfter(test.header, now); got != test.want {
+			t.Errorf("%q: expected %v, got %v", test.header, test.want, got)
+		}
+	}
+}
+
+func TestDelay(t *testing.T) {
+	c := New().WithBackoff(100*time.Millisecond, time.Second)
+	for attempt, ceiling := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for i := 0; i < 20; i++ {
+			if d := c.delay(attempt, 0); d < 0 || d >= ceiling {
+				t.Fatalf("attempt %d: expected a delay below %v, got %v", attempt, ceiling, d)
+			}
+		}
+	}
+	// a Retry-After is honoured, up to the longest backoff
+	if d := c.delay(0, 500*time.Millisecond); d != 500*time.Millisecond {
+		t.Fatalf("expected to wait as asked, got %v", d)
+	}
+	if d := c.delay(0, time.Hour); d != time.Second {
+		t.Fatalf("expected to wait at most a second, got %v", d)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	c := New().WithRetries(2).WithBackoff(time.Millisecond, time.Millisecond)
+
+	// connections reset are retried
+	var requests atomic.Int32
+	reset := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			_ = conn.Close()
+		}
+	}))
+	defer reset.Close()
+	if _, err := c.Get(reset.URL, nil); err == nil {
+		t.Fatalf("expected the connection to fail")
+	}
+	if got := requests.Load(); got != 3 {
+		t.Fatalf("expected a reset connection to be retried twice, got %d requests", got)
+	}
+
+	// certificates not trusted fail again however often they are retried
+	var conns atomic.Int32
+	untrusted := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	untrusted.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	untrusted.Config.ErrorLog = log.New(io.Discard, "", 0)
+	untrusted.StartTLS()
+	defer untrusted.Close()
+	if _, err := c.Get(untrusted.URL, nil); err == nil {
+		t.Fatalf("expected the certificate not to be trusted")
+	}
+	if got := conns.Load(); got != 1 {
+		t.Fatalf("expected a certificate error not to be retried, got %d connections", got)
+	}
+
+	// of the statuses, only 429 and server errors are retried
+	status := http.StatusBadGateway
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(status)
+	}))
+	defer s.Close()
+	for _, test := range []struct {
+		status   int
+		requests int32
+	}{
+		{http.StatusBadGateway, 3},
+		{http.StatusTooManyRequests, 3},
+		{http.StatusForbidden, 1},
+		{http.StatusNotFound, 1},
+	} {
+		requests.Store(0)
+		status = test.status
+		res, err := c.Get(s.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = res.Body.Close()
+		if res.StatusCode != test.status || requests.Load() != test.requests {
+			t.Fatalf("%d: expected %d requests, got %d with status %d", test.status, test.requests, requests.Load(), res.StatusCode)
+		}
+	}
+}
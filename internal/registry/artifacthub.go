@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"net/url"
+	"strings"
+)
+
+type ArtifactHub struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewArtifactHub() *ArtifactHub {
+	return &ArtifactHub{
+		uri:   "https://artifacthub.io/api/v1",
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *ArtifactHub) WithURL(uri string) *ArtifactHub {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *ArtifactHub) WithFetch(client *fetch.Client) *ArtifactHub {
+	c.fetch = client
+	return c
+}
+
+func (c *ArtifactHub) Name() string {
+	return "artifacthub.io"
+}
+
+// https://artifacthub.io/docs/api/
+// https://artifacthub.io/api/v1/repositories/search?kind=0&url=https%3A%2F%2Fcharts.bitnami.com%2Fbitnami
+// https://artifacthub.io/api/v1/packages/helm/bitnami/postgresql/12.1.6
+
+type ArtifactHubRepository struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type ArtifactHubPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	License string `json:"license"`
+}
+
+// Repository finds the helm repository Artifact Hub lists under the url, charts are looked up by its name
+func (c *ArtifactHub) Repository(repoURL string) (ArtifactHubRepository, error) {
+	var repos []ArtifactHubRepository
+	res, err := c.fetch.Get(fmt.Sprintf("%s/repositories/search?kind=0&url=%s", c.uri, url.QueryEscape(repoURL)), nil)
+	if err != nil {
+		return ArtifactHubRepository{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return ArtifactHubRepository{}, depsdev.StatusError(res.StatusCode)
+	}
+
+	if err = json.NewDecoder(res.Body).Decode(&repos); err != nil {
+		return ArtifactHubRepository{}, err
+	}
+	for _, r := range repos {
+		if strings.EqualFold(strings.TrimSuffix(r.URL, "/"), strings.TrimSuffix(repoURL, "/")) {
+			return r, nil
+		}
+	}
+	return ArtifactHubRepository{}, depsdev.ErrNotFound
+}
+
+func (c *ArtifactHub) Package(repo string, name string, version string) (ArtifactHubPackage, error) {
+	var p ArtifactHubPackage
+	res, err := c.fetch.Get(fmt.Sprintf("%s/packages/helm/%s/%s/%s", c.uri, url.PathEscape(repo), url.PathEscape(name), url.PathEscape(version)), nil)
+	if err != nil {
+		return p, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return p, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&p)
+	return p, err
+}
+
+// Licenses of charts named by their repository without scheme and chart name, charts.bitnami.com/bitnami/postgresql.
+// Artifact Hub lists https repositories by their url and oci repositories by the url of the chart itself.
+func (c *ArtifactHub) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.HELM {
+		return nil, fmt.Errorf("artifacthub.io does not serve %s packages", depType)
+	}
+	i := strings.LastIndex(name, "/")
+	if i < 0 {
+		return nil, depsdev.ErrNotFound
+	}
+	repoPath, chart := name[:i], name[i+1:]
+
+	repo, err := c.Repository("https://" + repoPath)
+	if errors.Is(err, depsdev.ErrNotFound) {
+		repo, err = c.Repository("oci://" + name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := c.Package(repo.Name, chart, version)
+	if err != nil {
+		return nil, err
+	}
+	if p.License == "" {
+		return nil, nil
+	}
+	return []string{p.License}, nil
+}
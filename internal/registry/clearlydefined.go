@@ -0,0 +1,184 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"github.com/modfin/henry/slicez"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+type ClearlyDefined struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewClearlyDefined() *ClearlyDefined {
+	return &ClearlyDefined{
+		uri:   "https://api.clearlydefined.io",
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *ClearlyDefined) WithURL(uri string) *ClearlyDefined {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *ClearlyDefined) WithFetch(client *fetch.Client) *ClearlyDefined {
+	c.fetch = client
+	return c
+}
+
+func (c *ClearlyDefined) Name() string {
+	return "clearlydefined.io"
+}
+
+// https://api.clearlydefined.io/api-docs/
+// https://api.clearlydefined.io/definitions/npm/npmjs/-/express/4.18.2
+// https://api.clearlydefined.io/definitions/maven/mavencentral/org.postgresql/postgresql/42.6.0
+
+type Definition struct {
+	Licensed struct {
+		// Declared is the curated license expression, NOASSERTION when it is not known
+		Declared string `json:"declared"`
+		Facets   struct {
+			Core struct {
+				Discovered struct {
+					Expressions []string `json:"expressions"`
+				} `json:"discovered"`
+			} `json:"core"`
+		} `json:"facets"`
+	} `json:"licensed"`
+	Scores struct {
+		Effective int `json:"effective"`
+	} `json:"scores"`
+}
+
+// cdTypes are the ClearlyDefined type and provider of the dep types it knows, and the separator of namespace and name
+var cdTypes = map[depsdev.DepType]struct {
+	_type, provider, sep string
+}{
+	depsdev.NPM:      {"npm", "npmjs", "/"},
+	depsdev.GO:       {"go", "golang", "/"},
+	depsdev.MAVEN:    {"maven", "mavencentral", ":"},
+	depsdev.CARGO:    {"crate", "cratesio", ""},
+	depsdev.NUGET:    {"nuget", "nuget", ""},
+	depsdev.PYPI:     {"pypi", "pypi", ""},
+	depsdev.RUBYGEMS: {"gem", "rubygems", ""},
+	depsdev.COMPOSER: {"composer", "packagist", "/"},
+	depsdev.ACTIONS:  {"git", "github", "/"},
+}
+
+// ServesClearlyDefined tells if ClearlyDefined knows the ecosystem of a dep type
+func ServesClearlyDefined(depType depsdev.DepType) bool {
+	_, ok := cdTypes[depType]
+	return ok
+}
+
+// Coordinates are the ClearlyDefined type/provider/namespace/name/revision of a dep, false for deps it does not know
+func Coordinates(depType depsdev.DepType, name string, version string) (string, bool) {
+	t, ok := cdTypes[depType]
+	// git revisions are commits, which only pinned actions are
+	if !ok || depType == depsdev.ACTIONS && !commit.MatchString(version) {
+		return "", false
+	}
+
+	namespace := "-"
+	if i := strings.LastIndex(name, t.sep); t.sep != "" && i >= 0 {
+		namespace, name = name[:i], name[i+1:]
+	}
+	return strings.Join([]string{t._type, t.provider, url.PathEscape(namespace), url.PathEscape(name), url.PathEscape(version)}, "/"), true
+}
+
+var commit = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+func (c *ClearlyDefined) Definition(depType depsdev.DepType, name string, version string) (Definition, error) {
+	var d Definition
+	coordinates, ok := Coordinates(depType, name, version)
+	if !ok {
+		return d, fmt.Errorf("clearlydefined.io does not serve %s packages", depType)
+	}
+	res, err := c.fetch.Get(fmt.Sprintf("%s/definitions/%s", c.uri, coordinates), nil)
+	if err != nil {
+		return d, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return d, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&d)
+	return d, err
+}
+
+// Licenses of a dep as curated by ClearlyDefined. Definitions are computed for any coordinates, those it has
+// never harvested come back without a declared license.
+func (c *ClearlyDefined) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	d, err := c.Definition(depType, name, version)
+	if err != nil {
+		return nil, err
+	}
+	if !known(d.Licensed.Declared) {
+		return nil, nil
+	}
+	return []string{d.Licensed.Declared}, nil
+}
+
+// Check compares the licenses found for a dep with those ClearlyDefined declares, and those it declares with
+// those it discovered in the files of the package, reporting where they disagree
+func (c *ClearlyDefined) Check(depType depsdev.DepType, name string, version string, licenses []string) ([]string, error) {
+	d, err := c.Definition(depType, name, version)
+	if err != nil {
+		return nil, err
+	}
+	declared := d.Licensed.Declared
+	if !known(declared) {
+		return nil, nil
+	}
+
+	var warnings []string
+	found := strings.Join(licenses, " AND ")
+	if !slicez.ContainsFunc(licenses, func(l string) bool { return strings.HasPrefix(l, "~") }) && !sameIDs(found, declared) {
+		warnings = append(warnings, fmt.Sprintf("licensed as %s, but clearlydefined.io declares %s (score %d)", found, declared, d.Scores.Effective))
+	}
+
+	discovered := slicez.Filter(d.Licensed.Facets.Core.Discovered.Expressions, known)
+	declaredIDs := licenseIDs(declared)
+	undeclared := slicez.Filter(discovered, func(e string) bool {
+		return !slicez.EveryFunc(licenseIDs(e), func(id string) bool {
+			return slicez.Contains(declaredIDs, id)
+		})
+	})
+	if len(undeclared) > 0 {
+		warnings = append(warnings, fmt.Sprintf("declared as %s, but clearlydefined.io discovered %s in its files (score %d)", declared, strings.Join(undeclared, ", "), d.Scores.Effective))
+	}
+	return warnings, nil
+}
+
+func known(expression string) bool {
+	return expression != "" && expression != "NOASSERTION" && expression != "NONE" && expression != "OTHER"
+}
+
+// licenseIDs are the license identifiers of an SPDX expression, in order
+func licenseIDs(expression string) []string {
+	fields := strings.Fields(strings.NewReplacer("(", " ", ")", " ").Replace(expression))
+	ids := slicez.Filter(fields, func(f string) bool {
+		switch strings.ToUpper(f) {
+		case "AND", "OR", "WITH":
+			return false
+		}
+		return true
+	})
+	return slicez.Sort(slicez.Uniq(ids))
+}
+
+func sameIDs(a string, b string) bool {
+	return slicez.Equal(licenseIDs(a), licenseIDs(b))
+}
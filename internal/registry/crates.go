@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"net/url"
+)
+
+// cratesRateLimit is the requests a second crates.io allows crawlers, ref. https://crates.io/data-access
+const cratesRateLimit = 1
+
+type Crates struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewCrates() *Crates {
+	return &Crates{
+		uri:   "https://crates.io/api/v1",
+		fetch: fetch.New().WithRateLimit(cratesRateLimit),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *Crates) WithURL(uri string) *Crates {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings of deps.dev. crates.io is
+// requested at most once a second whatever the rate limit of the client, as its crawler policy asks.
+func (c *Crates) WithFetch(client *fetch.Client) *Crates {
+	c.fetch = client.Clone().WithRateLimit(cratesRateLimit)
+	return c
+}
+
+func (c *Crates) Name() string {
+	return "crates.io"
+}
+
+// https://crates.io/data-access
+// https://crates.io/api/v1/crates/serde/1.0.193
+
+type CrateVersion struct {
+	Version struct {
+		Crate   string `json:"crate"`
+		Num     string `json:"num"`
+		License string `json:"license"`
+	} `json:"version"`
+}
+
+func (c *Crates) Version(name string, version string) (CrateVersion, error) {
+	var v CrateVersion
+	// crates.io refuses requests without a user agent, fetch identifies depot by one
+	res, err := c.fetch.Get(fmt.Sprintf("%s/crates/%s/%s", c.uri, url.PathEscape(name), url.PathEscape(version)), nil)
+	if err != nil {
+		return v, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return v, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&v)
+	return v, err
+}
+
+// Licenses of a crate version, the SPDX expression of its Cargo.toml
+func (c *Crates) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.CARGO {
+		return nil, fmt.Errorf("crates.io does not serve %s packages", depType)
+	}
+	v, err := c.Version(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if v.Version.License == "" {
+		return nil, nil
+	}
+	return []string{v.Version.License}, nil
+}
@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+type GitHub struct {
+	uri   string
+	token string
+	fetch *fetch.Client
+}
+
+// NewGitHub uses $GITHUB_TOKEN when set, anonymous requests are limited to 60 an hour
+func NewGitHub() *GitHub {
+	return &GitHub{
+		uri:   "https://api.github.com",
+		token: os.Getenv("GITHUB_TOKEN"),
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *GitHub) WithURL(uri string) *GitHub {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *GitHub) WithFetch(client *fetch.Client) *GitHub {
+	c.fetch = client
+	return c
+}
+
+func (c *GitHub) Name() string {
+	return "github.com"
+}
+
+// https://docs.github.com/en/rest/licenses/licenses#get-the-license-for-a-repository
+// https://api.github.com/repos/apple/swift-nio/license?ref=2.62.0
+
+type GitHubLicense struct {
+	License struct {
+		SpdxID string `json:"spdx_id"`
+	} `json:"license"`
+}
+
+// RepositoryLicense looks up the license GitHub detects for a repository at a tag, branch or commit
+func (c *GitHub) RepositoryLicense(owner string, repo string, ref string) (GitHubLicense, error) {
+	var l GitHubLicense
+
+	header := http.Header{"Accept": {"application/vnd.github+json"}}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	res, err := c.fetch.Get(fmt.Sprintf("%s/repos/%s/%s/license?ref=%s", c.uri, url.PathEscape(owner), url.PathEscape(repo), url.QueryEscape(ref)), header)
+	if err != nil {
+		return l, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return l, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&l)
+	return l, err
+}
+
+// Licenses of packages named by their repository, github.com/<owner>/<repo> or as actions <owner>/<repo>,
+// at the version as git ref
+func (c *GitHub) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	parts := strings.Split(name, "/")
+	if depType == depsdev.ACTIONS {
+		parts = append([]string{"github.com"}, parts...)
+	}
+	if len(parts) != 3 || !strings.EqualFold(parts[0], "github.com") {
+		return nil, depsdev.ErrNotFound
+	}
+	l, err := c.RepositoryLicense(parts[1], parts[2], version)
+	if err != nil {
+		return nil, err
+	}
+	// NOASSERTION is GitHub not recognising the license
+	if l.License.SpdxID == "" || l.License.SpdxID == "NOASSERTION" {
+		return nil, nil
+	}
+	return []string{l.License.SpdxID}, nil
+}
@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"net/url"
+)
+
+type Hex struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewHex() *Hex {
+	return &Hex{
+		uri:   "https://hex.pm/api",
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *Hex) WithURL(uri string) *Hex {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *Hex) WithFetch(client *fetch.Client) *Hex {
+	c.fetch = client
+	return c
+}
+
+func (c *Hex) Name() string {
+	return "hex.pm"
+}
+
+// https://github.com/hexpm/specifications/blob/main/apiary.apib
+// https://hex.pm/api/packages/jason
+
+type HexPackage struct {
+	Name string `json:"name"`
+	Meta struct {
+		Licenses []string `json:"licenses"`
+	} `json:"meta"`
+	Releases []struct {
+		Version string `json:"version"`
+	} `json:"releases"`
+}
+
+func (c *Hex) Package(name string) (HexPackage, error) {
+	var p HexPackage
+	res, err := c.fetch.Get(fmt.Sprintf("%s/packages/%s", c.uri, url.PathEscape(name)), nil)
+	if err != nil {
+		return p, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return p, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&p)
+	return p, err
+}
+
+// Licenses of a hex package. Hex keeps licenses as package metadata, as of the latest release,
+// so versions not among the releases are reported as not found.
+func (c *Hex) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.HEX {
+		return nil, fmt.Errorf("hex.pm does not serve %s packages", depType)
+	}
+	p, err := c.Package(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range p.Releases {
+		if r.Version == version {
+			return p.Meta.Licenses, nil
+		}
+	}
+	return nil, depsdev.ErrNotFound
+}
@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/modfin/depot/internal/deps/pom"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"github.com/modfin/depot/internal/spdx"
+	"strings"
+)
+
+type MavenCentral struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewMavenCentral() *MavenCentral {
+	return &MavenCentral{
+		uri:   "https://repo1.maven.org/maven2",
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, e.g. a maven repository of artifactory or nexus, or a stand-in in tests
+func (c *MavenCentral) WithURL(uri string) *MavenCentral {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *MavenCentral) WithFetch(client *fetch.Client) *MavenCentral {
+	c.fetch = client
+	return c
+}
+
+func (c *MavenCentral) Name() string {
+	return "repo1.maven.org"
+}
+
+// https://maven.apache.org/repository/layout.html
+// https://repo1.maven.org/maven2/org/postgresql/postgresql/42.6.0/postgresql-42.6.0.pom
+
+func (c *MavenCentral) POM(groupId string, artifactId string, version string) (pom.POM, error) {
+	var x pom.PomXML
+	res, err := c.fetch.Get(fmt.Sprintf("%s/%s/%s/%s/%s-%s.pom", c.uri, strings.ReplaceAll(groupId, ".", "/"), artifactId, version, artifactId, version), nil)
+	if err != nil {
+		return pom.POM{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return pom.POM{}, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = xml.NewDecoder(res.Body).Decode(&x)
+	return pom.POM{Content: &x}, err
+}
+
+// maxParents bounds the parent poms followed looking for licenses
+const maxParents = 5
+
+// Licenses of a maven artifact, group:artifact, from the licenses of its pom or those it inherits from its parents
+func (c *MavenCentral) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.MAVEN {
+		return nil, fmt.Errorf("maven repositories do not serve %s packages", depType)
+	}
+	groupId, artifactId, found := strings.Cut(name, ":")
+	if !found {
+		return nil, depsdev.ErrNotFound
+	}
+
+	for i := 0; i <= maxParents; i++ {
+		p, err := c.POM(groupId, artifactId, version)
+		if err != nil {
+			return nil, err
+		}
+		if names := p.Licenses(); len(names) > 0 {
+			var licenses []string
+			for _, n := range names {
+				if id, ok := spdx.FromName(n); ok {
+					licenses = append(licenses, id)
+					continue
+				}
+				// as deps.dev reports licenses it does not recognise
+				licenses = append(licenses, "non-standard")
+			}
+			return licenses, nil
+		}
+		parent := p.Content.Parent
+		if parent.ArtifactId == "" {
+			break
+		}
+		groupId, artifactId, version = parent.GroupId, parent.ArtifactId, parent.Version
+	}
+	return nil, nil
+}
@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"github.com/modfin/depot/internal/spdx"
+	"github.com/modfin/henry/slicez"
+	"net/url"
+	"strings"
+)
+
+type NPM struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewNPM() *NPM {
+	return &NPM{
+		uri:   "https://registry.npmjs.org",
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *NPM) WithURL(uri string) *NPM {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *NPM) WithFetch(client *fetch.Client) *NPM {
+	c.fetch = client
+	return c
+}
+
+func (c *NPM) Name() string {
+	return "registry.npmjs.org"
+}
+
+// https://github.com/npm/registry/blob/main/docs/REGISTRY-API.md
+// https://registry.npmjs.org/express/4.18.2
+// https://registry.npmjs.org/@types%2fnode/20.10.0
+
+type NPMVersion struct {
+	Name    string     `json:"name"`
+	Version string     `json:"version"`
+	License NPMLicense `json:"license"`
+	// Licenses is the deprecated form, a list of license objects
+	Licenses []NPMLicense `json:"licenses"`
+}
+
+// NPMLicense is an SPDX expression, or in old packages an object with the license as type
+type NPMLicense string
+
+func (l *NPMLicense) UnmarshalJSON(b []byte) error {
+	var s string
+	if json.Unmarshal(b, &s) == nil {
+		*l = NPMLicense(s)
+		return nil
+	}
+	var o struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &o); err != nil {
+		return err
+	}
+	*l = NPMLicense(o.Type)
+	return nil
+}
+
+func (c *NPM) Version(name string, version string) (NPMVersion, error) {
+	var v NPMVersion
+	// scoped packages keep the @ while the / is escaped
+	res, err := c.fetch.Get(fmt.Sprintf("%s/%s/%s", c.uri, strings.Replace(url.PathEscape(name), "%40", "@", 1), url.PathEscape(version)), nil)
+	if err != nil {
+		return v, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return v, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&v)
+	return v, err
+}
+
+// Licenses of an npm package version, as declared in its package.json
+func (c *NPM) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.NPM {
+		return nil, fmt.Errorf("npm registries do not serve %s packages", depType)
+	}
+	v, err := c.Version(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return v.SPDX(), nil
+}
+
+// SPDX is the licenses a package.json declares, those in a file of the package or proprietary are non-standard.
+// The deprecated licenses list names them in free text, names not recognised are non-standard.
+func (v NPMVersion) SPDX() []string {
+	var licenses []string
+	if s := strings.TrimSpace(string(v.License)); s != "" {
+		// the license is in a file of the package, or the package is proprietary
+		if strings.HasPrefix(strings.ToUpper(s), "SEE LICENSE IN") || s == "UNLICENSED" {
+			s = "non-standard"
+		}
+		licenses = append(licenses, s)
+	}
+	for _, l := range v.Licenses {
+		s := strings.TrimSpace(string(l))
+		if s == "" {
+			continue
+		}
+		id, ok := spdx.FromName(s)
+		if !ok {
+			id = "non-standard"
+		}
+		licenses = append(licenses, id)
+	}
+	return slicez.Uniq(licenses)
+}
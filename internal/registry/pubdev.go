@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"github.com/modfin/depot/internal/spdx"
+	"net/url"
+	"strings"
+)
+
+type PubDev struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewPubDev() *PubDev {
+	return &PubDev{
+		uri:   "https://pub.dev/api",
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *PubDev) WithURL(uri string) *PubDev {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *PubDev) WithFetch(client *fetch.Client) *PubDev {
+	c.fetch = client
+	return c
+}
+
+func (c *PubDev) Name() string {
+	return "pub.dev"
+}
+
+// https://github.com/dart-lang/pub/blob/master/doc/repository-spec-v2.md
+// https://pub.dev/api/packages/http/versions/1.1.0
+// https://pub.dev/api/packages/http/score
+
+type PubScore struct {
+	// Tags include the licenses pana detected in the package, e.g. license:bsd-3-clause
+	Tags []string `json:"tags"`
+}
+
+func (c *PubDev) get(path string, v any) error {
+	res, err := c.fetch.Get(c.uri+path, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return depsdev.StatusError(res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// Licenses of a pub package. The license analysis is only published for the latest version,
+// so the version is only checked to exist.
+func (c *PubDev) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.PUB {
+		return nil, fmt.Errorf("pub.dev does not serve %s packages", depType)
+	}
+
+	var v struct {
+		Version string `json:"version"`
+	}
+	err := c.get(fmt.Sprintf("/packages/%s/versions/%s", url.PathEscape(name), url.PathEscape(version)), &v)
+	if err != nil {
+		return nil, err
+	}
+
+	var score PubScore
+	err = c.get(fmt.Sprintf("/packages/%s/score", url.PathEscape(name)), &score)
+	if err != nil {
+		return nil, err
+	}
+
+	var licenses []string
+	for _, tag := range score.Tags {
+		l, found := strings.CutPrefix(tag, "license:")
+		// fsf-libre and osi-approved classify the license, unknown is what it says
+		if !found || l == "fsf-libre" || l == "osi-approved" || l == "unknown" {
+			continue
+		}
+		if id, ok := spdx.FromName(l); ok {
+			l = id
+		}
+		licenses = append(licenses, l)
+	}
+	return licenses, nil
+}
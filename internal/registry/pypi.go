@@ -0,0 +1,84 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/deps/pypi"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"net/url"
+)
+
+type PyPI struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewPyPI() *PyPI {
+	return &PyPI{
+		uri:   "https://pypi.org/pypi",
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *PyPI) WithURL(uri string) *PyPI {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *PyPI) WithFetch(client *fetch.Client) *PyPI {
+	c.fetch = client
+	return c
+}
+
+func (c *PyPI) Name() string {
+	return "pypi.org"
+}
+
+// https://warehouse.pypa.io/api-reference/json.html
+// https://pypi.org/pypi/requests/2.31.0/json
+
+type PyPIRelease struct {
+	Info struct {
+		Name              string   `json:"name"`
+		Version           string   `json:"version"`
+		License           string   `json:"license"`
+		LicenseExpression string   `json:"license_expression"`
+		Classifiers       []string `json:"classifiers"`
+	} `json:"info"`
+}
+
+func (c *PyPI) Release(name string, version string) (PyPIRelease, error) {
+	var r PyPIRelease
+	res, err := c.fetch.Get(fmt.Sprintf("%s/%s/%s/json", c.uri, url.PathEscape(name), url.PathEscape(version)), nil)
+	if err != nil {
+		return r, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return r, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&r)
+	return r, err
+}
+
+// Licenses of a pypi release, read from its metadata as for installed distributions
+func (c *PyPI) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.PYPI {
+		return nil, fmt.Errorf("pypi registries do not serve %s packages", depType)
+	}
+	r, err := c.Release(name, version)
+	if err != nil {
+		return nil, err
+	}
+	d := pypi.Distribution{
+		License:           r.Info.License,
+		LicenseExpression: r.Info.LicenseExpression,
+		Classifiers:       r.Info.Classifiers,
+	}
+	return d.SPDX(), nil
+}
@@ -0,0 +1,220 @@
+package registry
+
+import (
+	"errors"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"github.com/modfin/henry/slicez"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// standIn serves canned responses by request uri, anything else is not found
+func standIn(t *testing.T, responses map[string]string) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestTerraform(t *testing.T) {
+	s := standIn(t, map[string]string{
+		"/v1/providers/hashicorp/aws/5.31.0":                          `{"namespace":"hashicorp","name":"aws","version":"5.31.0","tag":"v5.31.0","source":"https://github.com/hashicorp/terraform-provider-aws"}`,
+		"/repos/hashicorp/terraform-provider-aws/license?ref=v5.31.0": `{"license":{"spdx_id":"MPL-2.0"}}`,
+	})
+	c := NewTerraform().WithURL(s.URL + "/v1/providers")
+	c.github.WithURL(s.URL)
+
+	got, err := c.Licenses(depsdev.TERRAFORM, "registry.terraform.io/hashicorp/aws", "5.31.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicez.Equal(got, []string{"MPL-2.0"}) {
+		t.Fatalf("expected MPL-2.0, got %v", got)
+	}
+
+	_, err = c.Licenses(depsdev.TERRAFORM, "registry.terraform.io/hashicorp/aws", "0.0.1")
+	if !errors.Is(err, depsdev.ErrNotFound) {
+		t.Fatalf("expected http status 404, got %v", err)
+	}
+}
+
+func TestArtifactHub(t *testing.T) {
+	s := standIn(t, map[string]string{
+		"/repositories/search?kind=0&url=https%3A%2F%2Fcharts.bitnami.com%2Fbitnami":               `[{"name":"bitnami","url":"https://charts.bitnami.com/bitnami"}]`,
+		"/repositories/search?kind=0&url=https%3A%2F%2Fregistry-1.docker.io%2Fbitnamicharts":       `[]`,
+		"/repositories/search?kind=0&url=oci%3A%2F%2Fregistry-1.docker.io%2Fbitnamicharts%2Fredis": `[{"name":"redis-oci","url":"oci://registry-1.docker.io/bitnamicharts/redis"}]`,
+		"/packages/helm/bitnami/postgresql/12.1.6":                                                 `{"name":"postgresql","version":"12.1.6","license":"Apache-2.0"}`,
+		"/packages/helm/redis-oci/redis/18.6.1":                                                    `{"name":"redis","version":"18.6.1","license":"Apache-2.0"}`,
+	})
+	c := NewArtifactHub().WithURL(s.URL)
+
+	for name, version := range map[string]string{
+		"charts.bitnami.com/bitnami/postgresql":    "12.1.6",
+		"registry-1.docker.io/bitnamicharts/redis": "18.6.1",
+	} {
+		got, err := c.Licenses(depsdev.HELM, name, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slicez.Equal(got, []string{"Apache-2.0"}) {
+			t.Fatalf("%s: expected Apache-2.0, got %v", name, got)
+		}
+	}
+}
+
+func TestNativeRegistries(t *testing.T) {
+	s := standIn(t, map[string]string{
+		"/npm/@types%2Fnode/20.10.0":   `{"name":"@types/node","version":"20.10.0","license":"MIT"}`,
+		"/npm/json-schema/0.2.3":       `{"name":"json-schema","version":"0.2.3","licenses":[{"type":"AFLv2.1"},{"type":"BSD"}]}`,
+		"/npm/sax/0.5.8":               `{"name":"sax","version":"0.5.8","licenses":[{"type":"MIT License"}]}`,
+		"/npm/internal-lib/1.0.0":      `{"name":"internal-lib","version":"1.0.0","license":"SEE LICENSE IN LICENSE.md"}`,
+		"/pypi/requests/2.31.0/json":   `{"info":{"name":"requests","version":"2.31.0","license":"Apache 2.0","classifiers":["License :: OSI Approved :: Apache Software License"]}}`,
+		"/pypi/attrs/23.2.0/json":      `{"info":{"name":"attrs","version":"23.2.0","license":"","license_expression":"MIT"}}`,
+		"/crates/crates/serde/1.0.193": `{"version":{"crate":"serde","num":"1.0.193","license":"MIT OR Apache-2.0"}}`,
+		"/maven/org/postgresql/postgresql/42.6.0/postgresql-42.6.0.pom": `<project><artifactId>postgresql</artifactId>
+			<licenses><license><name>BSD-2-Clause</name></license></licenses></project>`,
+		"/maven/io/netty/netty-codec/4.1.100.Final/netty-codec-4.1.100.Final.pom": `<project><artifactId>netty-codec</artifactId>
+			<parent><groupId>io.netty</groupId><artifactId>netty-parent</artifactId><version>4.1.100.Final</version></parent></project>`,
+		"/maven/io/netty/netty-parent/4.1.100.Final/netty-parent-4.1.100.Final.pom": `<project><artifactId>netty-parent</artifactId>
+			<licenses><license><name>Apache License, Version 2.0</name></license><license><name>Netty License</name></license></licenses></project>`,
+	})
+
+	tests := []struct {
+		provider interface {
+			Licenses(depsdev.DepType, string, string) ([]string, error)
+		}
+		depType depsdev.DepType
+		name    string
+		version string
+		want    []string
+	}{
+		{NewNPM().WithURL(s.URL + "/npm"), depsdev.NPM, "@types/node", "20.10.0", []string{"MIT"}},
+		{NewNPM().WithURL(s.URL + "/npm"), depsdev.NPM, "json-schema", "0.2.3", []string{"non-standard"}},
+		{NewNPM().WithURL(s.URL + "/npm"), depsdev.NPM, "sax", "0.5.8", []string{"MIT"}},
+		{NewNPM().WithURL(s.URL + "/npm"), depsdev.NPM, "internal-lib", "1.0.0", []string{"non-standard"}},
+		{NewPyPI().WithURL(s.URL + "/pypi"), depsdev.PYPI, "requests", "2.31.0", []string{"Apache-2.0"}},
+		{NewPyPI().WithURL(s.URL + "/pypi"), depsdev.PYPI, "attrs", "23.2.0", []string{"MIT"}},
+		{NewCrates().WithURL(s.URL + "/crates"), depsdev.CARGO, "serde", "1.0.193", []string{"MIT OR Apache-2.0"}},
+		{NewMavenCentral().WithURL(s.URL + "/maven"), depsdev.MAVEN, "org.postgresql:postgresql", "42.6.0", []string{"BSD-2-Clause"}},
+		{NewMavenCentral().WithURL(s.URL + "/maven"), depsdev.MAVEN, "io.netty:netty-codec", "4.1.100.Final", []string{"Apache-2.0", "non-standard"}},
+	}
+	for _, test := range tests {
+		got, err := test.provider.Licenses(test.depType, test.name, test.version)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !slicez.Equal(got, test.want) {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.want, got)
+		}
+	}
+
+	crates := NewCrates().WithURL(s.URL + "/crates").WithFetch(fetch.New().WithRateLimit(0))
+	_, err := crates.Licenses(depsdev.CARGO, "serde", "0.0.1")
+	if !errors.Is(err, depsdev.ErrNotFound) {
+		t.Fatalf("expected http status 404, got %v", err)
+	}
+
+	// crates.io is requested once a second, even by a client without a rate limit
+	start := time.Now()
+	if _, err := crates.Licenses(depsdev.CARGO, "serde", "1.0.193"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Fatalf("expected crates.io to be requested once a second, the second request took %v", elapsed)
+	}
+}
+
+func TestClearlyDefined(t *testing.T) {
+	s := standIn(t, map[string]string{
+		"/definitions/npm/npmjs/-/express/4.18.2":         `{"licensed":{"declared":"MIT","facets":{"core":{"discovered":{"expressions":["MIT"]}}}},"scores":{"effective":88}}`,
+		"/definitions/npm/npmjs/@types/node/20.10.0":      `{"licensed":{"declared":"MIT","facets":{"core":{"discovered":{"expressions":["MIT","Apache-2.0"]}}}},"scores":{"effective":71}}`,
+		"/definitions/crate/cratesio/-/unharvested/0.1.0": `{"licensed":{"declared":"NOASSERTION"}}`,
+	})
+	c := NewClearlyDefined().WithURL(s.URL)
+
+	for _, test := range []struct {
+		depType depsdev.DepType
+		name    string
+		version string
+		want    string
+		ok      bool
+	}{
+		{depsdev.NPM, "@types/node", "20.10.0", "npm/npmjs/@types/node/20.10.0", true},
+		{depsdev.GO, "github.com/modfin/henry", "v0.1.0", "go/golang/github.com%2Fmodfin/henry/v0.1.0", true},
+		{depsdev.MAVEN, "org.postgresql:postgresql", "42.6.0", "maven/mavencentral/org.postgresql/postgresql/42.6.0", true},
+		{depsdev.CARGO, "serde", "1.0.193", "crate/cratesio/-/serde/1.0.193", true},
+		{depsdev.ACTIONS, "actions/checkout", "b4ffde65f46336ab88eb53be808477a3936bae11", "git/github/actions/checkout/b4ffde65f46336ab88eb53be808477a3936bae11", true},
+		{depsdev.ACTIONS, "actions/checkout", "v4", "", false},
+		{depsdev.HELM, "charts.bitnami.com/bitnami/postgresql", "12.1.6", "", false},
+	} {
+		got, ok := Coordinates(test.depType, test.name, test.version)
+		if got != test.want || ok != test.ok {
+			t.Fatalf("%s %s: expected %s %v, got %s %v", test.depType, test.name, test.want, test.ok, got, ok)
+		}
+	}
+
+	got, err := c.Licenses(depsdev.NPM, "express", "4.18.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicez.Equal(got, []string{"MIT"}) {
+		t.Fatalf("expected MIT, got %v", got)
+	}
+	got, err = c.Licenses(depsdev.CARGO, "unharvested", "0.1.0")
+	if err != nil || len(got) != 0 {
+		t.Fatalf("expected no licenses, got %v %v", got, err)
+	}
+
+	warnings, err := c.Check(depsdev.NPM, "express", "4.18.2", []string{"MIT"})
+	if err != nil || len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got %v %v", warnings, err)
+	}
+	warnings, err = c.Check(depsdev.NPM, "@types/node", "20.10.0", []string{"ISC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"licensed as ISC, but clearlydefined.io declares MIT (score 71)",
+		"declared as MIT, but clearlydefined.io discovered Apache-2.0 in its files (score 71)",
+	}
+	if !slicez.Equal(warnings, want) {
+		t.Fatalf("expected %v, got %v", want, warnings)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "secret")
+	var requests []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Header.Get("User-Agent")+" "+r.Header.Get("Authorization"))
+		// the first request fails for the time being
+		if len(requests) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"license":{"spdx_id":"Apache-2.0"}}`))
+	}))
+	defer s.Close()
+
+	client := fetch.New().WithUserAgent("acme").WithBackoff(time.Millisecond, time.Millisecond)
+	got, err := NewGitHub().WithURL(s.URL).WithFetch(client).Licenses(depsdev.SWIFT, "github.com/apple/swift-nio", "2.62.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slicez.Equal(got, []string{"Apache-2.0"}) {
+		t.Fatalf("expected Apache-2.0, got %v", got)
+	}
+	if !slicez.Equal(requests, []string{"acme Bearer secret", "acme Bearer secret"}) {
+		t.Fatalf("expected the request to be retried with the user agent and token, got %q", requests)
+	}
+}
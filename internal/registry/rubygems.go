@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"net/url"
+	"strings"
+)
+
+type RubyGems struct {
+	uri   string
+	fetch *fetch.Client
+}
+
+func NewRubyGems() *RubyGems {
+	return &RubyGems{
+		uri:   "https://rubygems.org",
+		fetch: fetch.New(),
+	}
+}
+
+// WithURL points the client at a mirror, or a stand-in in tests
+func (c *RubyGems) WithURL(uri string) *RubyGems {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *RubyGems) WithFetch(client *fetch.Client) *RubyGems {
+	c.fetch = client
+	return c
+}
+
+func (c *RubyGems) Name() string {
+	return "rubygems.org"
+}
+
+// https://guides.rubygems.org/rubygems-org-api-v2/
+// https://rubygems.org/api/v2/rubygems/rails/versions/7.0.4.json
+// https://rubygems.org/api/v2/rubygems/nokogiri/versions/1.15.4.json?platform=x86_64-linux
+
+type RubyGemsVersion struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Platform string   `json:"platform"`
+	Licenses []string `json:"licenses"`
+}
+
+// Version looks up a gem version, the version may carry a platform suffix as in Gemfile.lock, e.g. 1.15.4-x86_64-linux
+func (c *RubyGems) Version(name string, version string) (RubyGemsVersion, error) {
+	var v RubyGemsVersion
+
+	u := fmt.Sprintf("%s/api/v2/rubygems/%s/versions/%s.json", c.uri, url.PathEscape(name), url.PathEscape(version))
+	if number, platform, found := strings.Cut(version, "-"); found {
+		u = fmt.Sprintf("%s/api/v2/rubygems/%s/versions/%s.json?platform=%s", c.uri, url.PathEscape(name), url.PathEscape(number), url.QueryEscape(platform))
+	}
+
+	res, err := c.fetch.Get(u, nil)
+	if err != nil {
+		return v, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return v, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&v)
+	return v, err
+}
+
+func (c *RubyGems) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.RUBYGEMS {
+		return nil, fmt.Errorf("rubygems.org does not serve %s packages", depType)
+	}
+	v, err := c.Version(name, version)
+	return v.Licenses, err
+}
@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/modfin/depot/internal/depsdev"
+	"github.com/modfin/depot/internal/fetch"
+	"net/url"
+	"strings"
+)
+
+type Terraform struct {
+	// uri overrides the provider registry of every host, https://<host>/v1/providers otherwise
+	uri    string
+	github *GitHub
+	fetch  *fetch.Client
+}
+
+// NewTerraform looks up providers in the registry of their source address, licenses are those GitHub detects
+// in the repository the provider is published from
+func NewTerraform() *Terraform {
+	return &Terraform{
+		github: NewGitHub(),
+		fetch:  fetch.New(),
+	}
+}
+
+// WithURL points the client at a provider registry, .../v1/providers, used whatever the host of the provider source
+func (c *Terraform) WithURL(uri string) *Terraform {
+	c.uri = uri
+	return c
+}
+
+// WithFetch makes the requests with the given client, e.g. one sharing the settings and rate limits of deps.dev
+func (c *Terraform) WithFetch(client *fetch.Client) *Terraform {
+	c.fetch = client
+	c.github.WithFetch(client)
+	return c
+}
+
+func (c *Terraform) Name() string {
+	return "registry.terraform.io"
+}
+
+// https://developer.hashicorp.com/terraform/internals/provider-registry-protocol
+// https://registry.terraform.io/v1/providers/hashicorp/aws/5.31.0
+
+type TerraformProvider struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Tag       string `json:"tag"`
+	// Source is the repository the provider is published from, https://github.com/hashicorp/terraform-provider-aws
+	Source string `json:"source"`
+}
+
+func (c *Terraform) Provider(host string, namespace string, name string, version string) (TerraformProvider, error) {
+	var p TerraformProvider
+	base := c.uri
+	if base == "" {
+		base = fmt.Sprintf("https://%s/v1/providers", host)
+	}
+	res, err := c.fetch.Get(fmt.Sprintf("%s/%s/%s/%s", base, url.PathEscape(namespace), url.PathEscape(name), url.PathEscape(version)), nil)
+	if err != nil {
+		return p, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode > 299 {
+		return p, depsdev.StatusError(res.StatusCode)
+	}
+
+	err = json.NewDecoder(res.Body).Decode(&p)
+	return p, err
+}
+
+// Licenses of providers named by their source address, registry.terraform.io/<namespace>/<type>
+func (c *Terraform) Licenses(depType depsdev.DepType, name string, version string) ([]string, error) {
+	if depType != depsdev.TERRAFORM {
+		return nil, fmt.Errorf("terraform registries do not serve %s packages", depType)
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) != 3 {
+		return nil, depsdev.ErrNotFound
+	}
+	p, err := c.Provider(parts[0], parts[1], parts[2], version)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := strings.TrimSuffix(strings.TrimPrefix(p.Source, "https://"), ".git")
+	tag := p.Tag
+	if tag == "" {
+		tag = "v" + version
+	}
+	return c.github.Licenses(depsdev.TERRAFORM, repo, tag)
+}
@@ -0,0 +1,170 @@
+package spdx
+
+import (
+	"embed"
+	"github.com/modfin/henry/slicez"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// corpus holds the license texts to classify by, named by SPDX id. texts are full licenses, or for the long ones
+// their opening passages, as found in LICENSE files. headers are the notices put at the top of source files.
+//
+//go:embed corpus/texts/*.txt corpus/headers/*.txt
+var corpus embed.FS
+
+// Threshold is the least confidence at which the classifier recognises a license
+const Threshold = 0.8
+
+// markerConfidence is the confidence of licenses recognised by the markers of FromText rather than by their text
+const markerConfidence = 0.5
+
+// Match is a license recognised in a text, with the share of the license text found in it as confidence
+type Match struct {
+	ID         string
+	Confidence float64
+	// File is the file the license was found in, relative to the directory classified
+	File string
+}
+
+type reference struct {
+	id       string
+	shingles map[string]bool
+}
+
+var texts, headers = loadCorpus("corpus/texts"), loadCorpus("corpus/headers")
+
+func loadCorpus(dir string) []reference {
+	entries, err := corpus.ReadDir(dir)
+	if err != nil {
+		panic(err)
+	}
+	var refs []reference
+	for _, e := range entries {
+		b, err := corpus.ReadFile(path.Join(dir, e.Name()))
+		if err != nil {
+			panic(err)
+		}
+		refs = append(refs, reference{
+			id:       strings.TrimSuffix(e.Name(), ".txt"),
+			shingles: shingles(string(b)),
+		})
+	}
+	return refs
+}
+
+var (
+	commentMarks  = regexp.MustCompile(`^[\s/*#;!\-{}<>]*`)
+	copyrightLine = regexp.MustCompile(`^(copyright|\(c\)|©)`)
+	nonWord       = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// words normalises a text to its words, lower case and without punctuation, comment markers and copyright
+// lines, which differ between copies of the same license
+func words(text string) []string {
+	var kept []string
+	for _, line := range strings.Split(strings.ToLower(text), "\n") {
+		line = commentMarks.ReplaceAllString(line, "")
+		if copyrightLine.MatchString(line) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	text = strings.ReplaceAll(strings.Join(kept, " "), "licence", "license")
+	return strings.Fields(nonWord.ReplaceAllString(text, " "))
+}
+
+// shingles are the runs of three consecutive words of a text
+func shingles(text string) map[string]bool {
+	w := words(text)
+	set := map[string]bool{}
+	for i := 0; i+3 <= len(w); i++ {
+		set[strings.Join(w[i:i+3], " ")] = true
+	}
+	return set
+}
+
+// containment is the share of the shingles of a that are also in b
+func containment(a, b map[string]bool) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	n := 0
+	for s := range a {
+		if b[s] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(a))
+}
+
+var identifierLine = regexp.MustCompile(`SPDX-License-Identifier:\s*(.+)`)
+
+// identifiers are the licenses stated by SPDX-License-Identifier lines, as in source file headers
+func identifiers(text string) []Match {
+	var matches []Match
+	for _, m := range identifierLine.FindAllStringSubmatch(text, -1) {
+		id := strings.TrimSpace(m[1])
+		id = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(id, "*/"), "-->"))
+		if id != "" {
+			matches = append(matches, Match{ID: id, Confidence: 1})
+		}
+	}
+	return matches
+}
+
+// Classify recognises the licenses of a text, by SPDX-License-Identifier lines or by similarity to the
+// license texts and notices depot knows. Matches are ordered by how much of the text they explain. A text
+// with a license and the notice of another, as MIT text under an Apache-2.0 header, is both, while notices
+// a license text carries itself, as the one at the end of GPL-3.0-only, are not found on their own.
+func Classify(text string) []Match {
+	if matches := identifiers(text); len(matches) > 0 {
+		return slicez.UniqBy(matches, func(m Match) string {
+			return m.ID
+		})
+	}
+	refs := append(append([]reference{}, texts...), headers...)
+	return slicez.UniqBy(classify(shingles(text), refs), func(m Match) string {
+		return m.ID
+	})
+}
+
+func classify(doc map[string]bool, refs []reference) []Match {
+	type candidate struct {
+		reference
+		confidence float64
+	}
+	var candidates []candidate
+	for _, ref := range refs {
+		if c := containment(ref.shingles, doc); c >= Threshold {
+			candidates = append(candidates, candidate{ref, c})
+		}
+	}
+	// the licenses explaining more of the text come first, a text with all of BSD-3-Clause but its own
+	// wording of the third clause is still more BSD-3-Clause than BSD-2-Clause
+	explained := func(c candidate) float64 {
+		return c.confidence * float64(len(c.shingles))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if explained(candidates[i]) != explained(candidates[j]) {
+			return explained(candidates[i]) > explained(candidates[j])
+		}
+		return candidates[i].confidence > candidates[j].confidence
+	})
+
+	// licenses that are mostly another license found, as BSD-2-Clause is of BSD-3-Clause, are not found on their own
+	var accepted []candidate
+	for _, c := range candidates {
+		if slicez.ContainsFunc(accepted, func(a candidate) bool {
+			return containment(c.shingles, a.shingles) >= Threshold
+		}) {
+			continue
+		}
+		accepted = append(accepted, c)
+	}
+	return slicez.Map(accepted, func(c candidate) Match {
+		return Match{ID: c.id, Confidence: c.confidence}
+	})
+}
@@ -0,0 +1,77 @@
+package spdx
+
+import (
+	"github.com/google/go-cmp/cmp"
+	"github.com/modfin/henry/slicez"
+	"testing"
+)
+
+func ids(matches []Match) []string {
+	return slicez.Map(matches, func(m Match) string {
+		return m.ID
+	})
+}
+
+// Every license of the corpus is recognised as itself only, the ones of similar licenses notwithstanding
+func TestClassifyCorpus(t *testing.T) {
+	for _, dir := range []string{"corpus/texts", "corpus/headers"} {
+		entries, err := corpus.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			b, err := corpus.ReadFile(dir + "/" + e.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{e.Name()[:len(e.Name())-len(".txt")]}
+			if got := ids(Classify(string(b))); !slicez.Equal(got, want) {
+				t.Errorf("%s/%s: expected %v, got %v", dir, e.Name(), want, got)
+			}
+		}
+	}
+}
+
+func TestClassifyDir(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want []Match
+	}{
+		{"testdata/bsd", []Match{{ID: "BSD-3-Clause", File: "LICENSE"}}},
+		{"testdata/gpl", []Match{{ID: "GPL-2.0-only", File: "COPYING"}}},
+		{"testdata/headers", []Match{
+			{ID: "Apache-2.0", File: "src/server.go"},
+			{ID: "MIT", Confidence: 1, File: "src/util.js"},
+		}},
+	}
+	for _, test := range tests {
+		got := ClassifyDir(test.dir)
+		for i := range got {
+			if got[i].Confidence < Threshold {
+				t.Errorf("%s: %s recognised with confidence %.2f", test.dir, got[i].ID, got[i].Confidence)
+			}
+			if test.want[i].Confidence == 0 {
+				got[i].Confidence = 0
+			}
+		}
+		if diff := cmp.Diff(test.want, got); diff != "" {
+			t.Errorf("%s: %s", test.dir, diff)
+		}
+	}
+}
+
+// A license text under the notice of another license is both
+func TestClassifyTextAndHeader(t *testing.T) {
+	mit, err := corpus.ReadFile("corpus/texts/MIT.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	apache, err := corpus.ReadFile("corpus/headers/Apache-2.0.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := slicez.Sort(ids(Classify(string(apache) + "\n\n" + string(mit))))
+	if want := []string{"Apache-2.0", "MIT"}; !slicez.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
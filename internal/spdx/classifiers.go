@@ -0,0 +1,37 @@
+package spdx
+
+import "strings"
+
+// Python trove classifiers, ref. https://pypi.org/classifiers/. Those not telling the variant or version, as
+// BSD License or Apache Software License, are left out, for the license files to tell.
+var classifiers = map[string]string{
+	"License :: OSI Approved :: MIT License":                                             "MIT",
+	"License :: OSI Approved :: MIT No Attribution License (MIT-0)":                      "MIT-0",
+	"License :: OSI Approved :: ISC License (ISCL)":                                      "ISC",
+	"License :: OSI Approved :: Mozilla Public License 2.0 (MPL 2.0)":                    "MPL-2.0",
+	"License :: OSI Approved :: Python Software Foundation License":                      "PSF-2.0",
+	"License :: OSI Approved :: The Unlicense (Unlicense)":                               "Unlicense",
+	"License :: OSI Approved :: Eclipse Public License 2.0 (EPL-2.0)":                    "EPL-2.0",
+	"License :: OSI Approved :: zlib/libpng License":                                     "Zlib",
+	"License :: OSI Approved :: Historical Permission Notice and Disclaimer (HPND)":      "HPND",
+	"License :: OSI Approved :: Boost Software License 1.0 (BSL-1.0)":                    "BSL-1.0",
+	"License :: OSI Approved :: Universal Permissive License (UPL)":                      "UPL-1.0",
+	"License :: OSI Approved :: GNU Lesser General Public License v2 (LGPLv2)":           "LGPL-2.0-only",
+	"License :: OSI Approved :: GNU Lesser General Public License v2 or later (LGPLv2+)": "LGPL-2.0-or-later",
+	"License :: OSI Approved :: GNU Lesser General Public License v3 (LGPLv3)":           "LGPL-3.0-only",
+	"License :: OSI Approved :: GNU Lesser General Public License v3 or later (LGPLv3+)": "LGPL-3.0-or-later",
+	"License :: OSI Approved :: GNU General Public License v2 (GPLv2)":                   "GPL-2.0-only",
+	"License :: OSI Approved :: GNU General Public License v2 or later (GPLv2+)":         "GPL-2.0-or-later",
+	"License :: OSI Approved :: GNU General Public License v3 (GPLv3)":                   "GPL-3.0-only",
+	"License :: OSI Approved :: GNU General Public License v3 or later (GPLv3+)":         "GPL-3.0-or-later",
+	"License :: OSI Approved :: GNU Affero General Public License v3":                    "AGPL-3.0-only",
+	"License :: OSI Approved :: GNU Affero General Public License v3 or later (AGPLv3+)": "AGPL-3.0-or-later",
+	"License :: CC0 1.0 Universal (CC0 1.0) Public Domain Dedication":                    "CC0-1.0",
+	"License :: Public Domain":                                                           "LicenseRef-Public-Domain",
+}
+
+// FromClassifier maps a python License :: classifier to its SPDX identifier
+func FromClassifier(classifier string) (string, bool) {
+	id, ok := classifiers[strings.Join(strings.Fields(classifier), " ")]
+	return id, ok
+}
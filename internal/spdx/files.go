@@ -0,0 +1,133 @@
+package spdx
+
+import (
+	"github.com/modfin/henry/slicez"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var licenseFileName = regexp.MustCompile(`(?i)^(licen[cs]e|copying|notice|unlicense)`)
+
+var sourceExtensions = map[string]bool{
+	".go": true, ".js": true, ".mjs": true, ".cjs": true, ".ts": true, ".py": true, ".rb": true, ".rs": true,
+	".java": true, ".kt": true, ".scala": true, ".swift": true, ".dart": true, ".ex": true, ".exs": true,
+	".erl": true, ".c": true, ".h": true, ".cc": true, ".cpp": true, ".hpp": true, ".cs": true, ".php": true,
+	".sh": true, ".tf": true,
+}
+
+const (
+	// maxSourceFiles is how many source files are read for license headers, when there are no license files
+	maxSourceFiles = 100
+	// headerSize is how much of the start of a source file is read for its license header
+	headerSize = 4096
+)
+
+// ClassifyDir recognises the licenses of a package directory from its LICENSE, LICENCE, COPYING and NOTICE
+// files, or, when it has none depot recognises, from the license headers of its source files
+func ClassifyDir(dir string) []Match {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var matches []Match
+	for _, e := range entries {
+		if e.IsDir() || !licenseFileName.MatchString(e.Name()) {
+			continue
+		}
+		text, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		found := Classify(string(text))
+		if len(found) == 0 {
+			if id, ok := fromMarkers(string(text)); ok {
+				found = []Match{{ID: id, Confidence: markerConfidence}}
+			}
+		}
+		matches = append(matches, inFile(found, e.Name())...)
+	}
+	if len(matches) == 0 {
+		matches = classifyHeaders(dir)
+	}
+	return best(matches)
+}
+
+// classifyHeaders recognises the licenses in the headers of the source files of a directory, skipping
+// hidden, vendored and test data directories
+func classifyHeaders(dir string) []Match {
+	var matches []Match
+	read := 0
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != dir && (strings.HasPrefix(name, ".") || name == "vendor" || name == "node_modules" || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !sourceExtensions[filepath.Ext(path)] {
+			return nil
+		}
+		if read == maxSourceFiles {
+			return filepath.SkipAll
+		}
+		read++
+
+		header, err := readHeader(path)
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		matches = append(matches, inFile(Classify(header), filepath.ToSlash(rel))...)
+		return nil
+	})
+	return matches
+}
+
+func readHeader(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b, err := io.ReadAll(io.LimitReader(f, headerSize))
+	return string(b), err
+}
+
+func inFile(matches []Match, file string) []Match {
+	for i := range matches {
+		matches[i].File = file
+	}
+	return matches
+}
+
+// best keeps the most confident match of every license, ordered by id
+func best(matches []Match) []Match {
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Confidence > matches[j].Confidence
+	})
+	matches = slicez.UniqBy(matches, func(m Match) string {
+		return m.ID
+	})
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].ID < matches[j].ID
+	})
+	return matches
+}
+
+// FromDir recognises the licenses of a package directory, as ClassifyDir does
+func FromDir(dir string) ([]string, bool) {
+	ids := slicez.Map(ClassifyDir(dir), func(m Match) string {
+		return m.ID
+	})
+	return ids, len(ids) > 0
+}
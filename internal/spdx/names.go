@@ -0,0 +1,80 @@
+package spdx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Package metadata, e.g. maven poms and python distributions, often state license names in free text.
+// These are the spellings commonly seen on maven central and pypi. Names that do not tell the variant or version,
+// such as bsd, apache software license or lgpl 2.1, which may be -only or -or-later, are left out rather than guessed.
+var licenseNames = map[string]string{
+	"apache 2":                                "Apache-2.0",
+	"apache 2.0":                              "Apache-2.0",
+	"apache license 2.0":                      "Apache-2.0",
+	"apache license version 2.0":              "Apache-2.0",
+	"apache license v2.0":                     "Apache-2.0",
+	"apache software license version 2.0":     "Apache-2.0",
+	"the apache license version 2.0":          "Apache-2.0",
+	"the apache software license version 2.0": "Apache-2.0",
+	"apache-2.0":                              "Apache-2.0",
+	"asl 2.0":                                 "Apache-2.0",
+	"isc":                                     "ISC",
+	"isc license":                             "ISC",
+	"python software foundation license":      "PSF-2.0",
+	"psf":                                     "PSF-2.0",
+	"mpl-2.0":                                 "MPL-2.0",
+	"mit":                                     "MIT",
+	"mit license":                             "MIT",
+	"the mit license":                         "MIT",
+	"new bsd license":                         "BSD-3-Clause",
+	"bsd 3-clause":                            "BSD-3-Clause",
+	"bsd-3-clause":                            "BSD-3-Clause",
+	"the bsd 3-clause license":                "BSD-3-Clause",
+	"revised bsd":                             "BSD-3-Clause",
+	"bsd 2-clause":                            "BSD-2-Clause",
+	"bsd-2-clause":                            "BSD-2-Clause",
+	"simplified bsd license":                  "BSD-2-Clause",
+	"eclipse public license 1.0":              "EPL-1.0",
+	"eclipse public license v1.0":             "EPL-1.0",
+	"eclipse public license - v 1.0":          "EPL-1.0",
+	"epl 1.0":                                 "EPL-1.0",
+	"eclipse public license 2.0":              "EPL-2.0",
+	"eclipse public license v2.0":             "EPL-2.0",
+	"eclipse public license - v 2.0":          "EPL-2.0",
+	"epl 2.0":                                 "EPL-2.0",
+	"eclipse distribution license 1.0":        "BSD-3-Clause",
+	"eclipse distribution license - v 1.0":    "BSD-3-Clause",
+	"edl 1.0":                                 "BSD-3-Clause",
+	"lgpl-2.1":                                "LGPL-2.1-only",
+	"gpl2 w/ cpe":                             "GPL-2.0-only WITH Classpath-exception-2.0",
+	"gnu general public license version 2 with the classpath exception": "GPL-2.0-only WITH Classpath-exception-2.0",
+	"cddl 1.0":                              "CDDL-1.0",
+	"cddl 1.1":                              "CDDL-1.1",
+	"cddl + gplv2 with classpath exception": "CDDL-1.1 OR GPL-2.0-only WITH Classpath-exception-2.0",
+	"mozilla public license 2.0":            "MPL-2.0",
+	"mpl 2.0":                               "MPL-2.0",
+	"public domain":                         "LicenseRef-Public-Domain",
+	"cc0":                                   "CC0-1.0",
+	"cc0 1.0 universal":                     "CC0-1.0",
+	"the unlicense":                         "Unlicense",
+	"unlicense":                             "Unlicense",
+	"bouncy castle licence":                 "MIT",
+	"go license":                            "BSD-3-Clause",
+	"the go license":                        "BSD-3-Clause",
+	"indiana university extreme! lab software": "IU-Extreme-1.1.1",
+}
+
+var licenseNameNoise = regexp.MustCompile(`[,()"]`)
+
+// FromName maps a license name, as stated in package metadata, to its SPDX identifier
+func FromName(name string) (string, bool) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	n = licenseNameNoise.ReplaceAllString(n, "")
+	n = strings.Join(strings.Fields(n), " ")
+
+	if id, ok := licenseNames[n]; ok {
+		return id, true
+	}
+	return "", false
+}
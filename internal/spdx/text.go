@@ -0,0 +1,39 @@
+package spdx
+
+import (
+	"github.com/modfin/henry/slicez"
+	"strings"
+)
+
+var licenseTexts = []struct {
+	id      string
+	markers []string
+}{
+	{"Apache-2.0", []string{"apache license", "version 2.0"}},
+	{"MIT", []string{"permission is hereby granted, free of charge"}},
+	{"EPL-2.0", []string{"eclipse public license - v 2.0"}},
+	{"EPL-1.0", []string{"eclipse public license - v 1.0"}},
+	{"BSD-3-Clause", []string{"redistribution and use in source and binary forms", "neither the name of"}},
+	{"BSD-2-Clause", []string{"redistribution and use in source and binary forms"}},
+}
+
+// FromText recognises the license of a text, the most confident match of the classifier, or failing that
+// by the markers of the handful of license texts commonly bundled with packages
+func FromText(text string) (string, bool) {
+	if matches := Classify(text); len(matches) > 0 {
+		return matches[0].ID, true
+	}
+	return fromMarkers(text)
+}
+
+func fromMarkers(text string) (string, bool) {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+	for _, l := range licenseTexts {
+		if slicez.EveryFunc(l.markers, func(m string) bool {
+			return strings.Contains(text, m)
+		}) {
+			return l.id, true
+		}
+	}
+	return "", false
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"github.com/modfin/henry/mapz"
 	"github.com/modfin/henry/slicez"
+	"gopkg.in/yaml.v3"
 	"strings"
+	"time"
 )
 
 type SPDX string
@@ -81,6 +83,46 @@ type Config struct {
 		Ignore   []Dependency `yaml:"ignore"`
 		Licenses []Dependency `yaml:"licenses"`
 	} `yaml:"dependency"`
+
+	// Providers are asked in order for the licenses of a dependency, until one knows them
+	Providers []Provider `yaml:"providers"`
+
+	DepsDev DepsDev `yaml:"depsdev"`
+}
+
+// DepsDev is how depot reaches deps.dev, the api url, e.g. of a mirror, how long to wait for it, the user agent
+// to identify by, a proxy to go through, how many times to retry failing requests and how many requests to make
+// a second at most. Unset values are the defaults of depsdev.Client.
+type DepsDev struct {
+	URL       string        `yaml:"url"`
+	Timeout   time.Duration `yaml:"timeout"`
+	UserAgent string        `yaml:"useragent"`
+	Proxy     string        `yaml:"proxy"`
+	Retries   *int          `yaml:"retries"`
+	RateLimit float64       `yaml:"ratelimit"`
+}
+
+// Provider is a license provider by name, local, reading packages already fetched, deps.dev, registry, the registry
+// of the ecosystem, clearlydefined, or classifier, recognising the license texts of packages in a local directory.
+// It may be limited to a single dep type, and pointed at a mirror by url, or the classifier at a directory and
+// local at a node_modules directory by path.
+// A provider used to cross-check is not asked for licenses, but reports where it disagrees with the licenses found.
+type Provider struct {
+	Name       string `yaml:"name"`
+	Type       string `yaml:"type"`
+	URL        string `yaml:"url"`
+	Path       string `yaml:"path"`
+	CrossCheck bool   `yaml:"crosscheck"`
+}
+
+// UnmarshalYAML accepts providers given by name only, as well as by mapping
+func (p *Provider) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		p.Name = value.Value
+		return nil
+	}
+	type provider Provider
+	return value.Decode((*provider)(p))
 }
 
 type Dependency struct {